@@ -1,3 +1,5 @@
 module lbox
 
 go 1.22.1
+
+require gopkg.in/yaml.v3 v3.0.1
@@ -0,0 +1,357 @@
+// Package circuitbreaker implements the closed/open/half-open circuit breaker
+// on top of statemachine.StateMachine. Besides being a ready to use tool it
+// doubles as an example of guards, named events, payloads and listeners.
+package circuitbreaker
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"lbox/pkg/clock"
+	"lbox/pkg/statemachine"
+)
+
+// ErrOpen is returned while the breaker rejects calls
+var ErrOpen = errors.New("circuit breaker is open")
+
+// ErrTooManyProbes is returned in half-open when all probe slots are in use
+var ErrTooManyProbes = errors.New("circuit breaker half-open probe limit reached")
+
+// State is the name of a breaker state
+type State string
+
+const (
+	StateClosed   State = "closed"
+	StateOpen     State = "open"
+	StateHalfOpen State = "half-open"
+)
+
+// Events driving the underlying state machine
+const (
+	eventTrip  = "trip"
+	eventProbe = "probe"
+	eventReset = "reset"
+)
+
+// Settings configures a Breaker, zero values select the defaults
+type Settings struct {
+	// Window is the rolling period over which the failure rate is computed,
+	// defaults to 10s
+	Window time.Duration
+	// Buckets splits Window into slots that expire one at a time, defaults to 10
+	Buckets int
+	// MinRequests is the number of calls in the window needed before the
+	// breaker may trip, defaults to 10
+	MinRequests int
+	// FailureRate in (0, 1] trips the breaker when reached, defaults to 0.5
+	FailureRate float64
+	// OpenTimeout is how long the breaker stays open before probing,
+	// defaults to 30s
+	OpenTimeout time.Duration
+	// HalfOpenProbes caps concurrent calls in half-open, defaults to 1
+	HalfOpenProbes int
+	// HalfOpenSuccesses closes the breaker after that many successful probes,
+	// defaults to HalfOpenProbes
+	HalfOpenSuccesses int
+	// IsFailure classifies call errors, defaults to err != nil
+	IsFailure func(err error) bool
+	// Clock is the time source, defaults to the system clock
+	Clock clock.Clock
+}
+
+// Change describes a state change reported to listeners
+type Change struct {
+	From   State
+	To     State
+	Reason string
+	At     time.Time
+}
+
+// Breaker guards calls to a dependency and stops them while it is failing
+type Breaker struct {
+	settings   Settings
+	sm         *statemachine.StateMachine
+	window     *window
+	openedAt   time.Time
+	inFlight   int
+	successes  int
+	generation uint64
+	listeners  []func(Change)
+	pending    []Change
+	mu         sync.Mutex
+}
+
+// New creates a closed Breaker
+func New(settings Settings) *Breaker {
+	settings = withDefaults(settings)
+	b := &Breaker{
+		settings: settings,
+		window:   newWindow(settings.Window, settings.Buckets),
+	}
+
+	b.sm = statemachine.NewStateMachine(b, statemachine.StateMap{
+		States: map[string]statemachine.State{
+			string(StateClosed):   &breakerState{name: StateClosed, b: b},
+			string(StateOpen):     &breakerState{name: StateOpen, b: b},
+			string(StateHalfOpen): &breakerState{name: StateHalfOpen, b: b},
+		},
+	})
+	for _, rule := range []struct {
+		event    string
+		from, to State
+	}{
+		{eventTrip, StateClosed, StateOpen},
+		{eventTrip, StateHalfOpen, StateOpen},
+		{eventProbe, StateOpen, StateHalfOpen},
+		{eventReset, StateHalfOpen, StateClosed},
+	} {
+		if err := b.sm.AddTransition(rule.event, string(rule.from), string(rule.to)); err != nil {
+			panic(fmt.Sprintf("circuitbreaker: invalid transition table: %v", err))
+		}
+	}
+	b.sm.OnTransition(b.transitioned)
+
+	if err := b.sm.Start(string(StateClosed)); err != nil {
+		panic(fmt.Sprintf("circuitbreaker: start failed: %v", err))
+	}
+	b.pending = nil
+	return b
+}
+
+func withDefaults(s Settings) Settings {
+	if s.Window <= 0 {
+		s.Window = 10 * time.Second
+	}
+	if s.Buckets <= 0 {
+		s.Buckets = 10
+	}
+	if s.MinRequests <= 0 {
+		s.MinRequests = 10
+	}
+	if s.FailureRate <= 0 || s.FailureRate > 1 {
+		s.FailureRate = 0.5
+	}
+	if s.OpenTimeout <= 0 {
+		s.OpenTimeout = 30 * time.Second
+	}
+	if s.HalfOpenProbes <= 0 {
+		s.HalfOpenProbes = 1
+	}
+	if s.HalfOpenSuccesses <= 0 {
+		s.HalfOpenSuccesses = s.HalfOpenProbes
+	}
+	if s.IsFailure == nil {
+		s.IsFailure = func(err error) bool { return err != nil }
+	}
+	if s.Clock == nil {
+		s.Clock = clock.New()
+	}
+	return s
+}
+
+// OnStateChange registers fn to be called after every state change. Listeners
+// run outside the breaker lock and may call back into it
+func (b *Breaker) OnStateChange(fn func(Change)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.listeners = append(b.listeners, fn)
+}
+
+// State returns the current state, moving an expired open breaker to half-open
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	b.advance()
+	state := b.current()
+	changes, listeners := b.takePending()
+	b.mu.Unlock()
+
+	notify(changes, listeners)
+	return state
+}
+
+// Allow reserves a call. On success the caller must report the outcome of the
+// call through done
+func (b *Breaker) Allow() (done func(err error), err error) {
+	b.mu.Lock()
+	b.advance()
+
+	switch b.current() {
+	case StateOpen:
+		err = ErrOpen
+	case StateHalfOpen:
+		if b.inFlight >= b.settings.HalfOpenProbes {
+			err = ErrTooManyProbes
+		}
+	}
+
+	if err == nil {
+		b.inFlight++
+		generation := b.generation
+		var once sync.Once
+		done = func(callErr error) {
+			once.Do(func() { b.done(generation, callErr) })
+		}
+	}
+
+	changes, listeners := b.takePending()
+	b.mu.Unlock()
+
+	notify(changes, listeners)
+	return done, err
+}
+
+// Execute runs fn when the breaker allows it and records its outcome
+func (b *Breaker) Execute(fn func() error) error {
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			done(fmt.Errorf("panic: %v", r))
+			panic(r)
+		}
+	}()
+
+	err = fn()
+	done(err)
+	return err
+}
+
+// done records the outcome of a call admitted in generation
+func (b *Breaker) done(generation uint64, err error) {
+	b.mu.Lock()
+
+	// Results of calls admitted before the last state change are stale
+	if generation == b.generation {
+		b.inFlight--
+		failed := b.settings.IsFailure(err)
+
+		switch b.current() {
+		case StateClosed:
+			now := b.settings.Clock.Now()
+			b.window.record(now, failed)
+			total, failures := b.window.totals(now)
+			rate := float64(failures) / float64(total)
+			if total >= b.settings.MinRequests && rate >= b.settings.FailureRate {
+				b.fire(eventTrip, fmt.Sprintf("failure rate %.2f over %d calls", rate, total))
+			}
+		case StateHalfOpen:
+			if failed {
+				b.fire(eventTrip, "probe failed")
+				break
+			}
+			b.successes++
+			if b.successes >= b.settings.HalfOpenSuccesses {
+				b.fire(eventReset, fmt.Sprintf("%d probes succeeded", b.successes))
+			}
+		}
+	}
+
+	changes, listeners := b.takePending()
+	b.mu.Unlock()
+
+	notify(changes, listeners)
+}
+
+// advance moves an open breaker to half-open once OpenTimeout has elapsed
+// Note: This method assumes the caller holds mu
+func (b *Breaker) advance() {
+	if b.current() != StateOpen {
+		return
+	}
+	if b.settings.Clock.Now().Sub(b.openedAt) >= b.settings.OpenTimeout {
+		b.fire(eventProbe, "open timeout elapsed")
+	}
+}
+
+// fire triggers a transition on the underlying state machine
+// Note: This method assumes the caller holds mu
+func (b *Breaker) fire(event, reason string) {
+	if err := b.sm.Fire(event, reason); err != nil {
+		panic(fmt.Sprintf("circuitbreaker: %s failed: %v", event, err))
+	}
+}
+
+// current returns the state of the underlying state machine
+func (b *Breaker) current() State {
+	return State(b.sm.GetCurrentState().GetName())
+}
+
+// enter resets the bookkeeping of the state being entered
+// Note: This method runs as a state hook while the caller holds mu
+func (b *Breaker) enter(state State) {
+	b.generation++
+	b.inFlight = 0
+	b.successes = 0
+	switch state {
+	case StateClosed:
+		b.window.reset()
+	case StateOpen:
+		b.openedAt = b.settings.Clock.Now()
+	}
+}
+
+// transitioned queues a state change for the listeners
+// Note: This method runs as a state machine listener while the caller holds mu
+func (b *Breaker) transitioned(t statemachine.Transition) {
+	if t.From == nil {
+		return
+	}
+	reason, _ := t.Payload.(string)
+	b.pending = append(b.pending, Change{
+		From:   State(t.From.GetName()),
+		To:     State(t.To.GetName()),
+		Reason: reason,
+		At:     b.settings.Clock.Now(),
+	})
+}
+
+// takePending hands over the queued changes and the listeners to notify
+// Note: This method assumes the caller holds mu
+func (b *Breaker) takePending() ([]Change, []func(Change)) {
+	changes := b.pending
+	b.pending = nil
+	return changes, b.listeners
+}
+
+func notify(changes []Change, listeners []func(Change)) {
+	for _, c := range changes {
+		for _, fn := range listeners {
+			fn(c)
+		}
+	}
+}
+
+// InitData implements statemachine.StateMachineInterface
+func (b *Breaker) InitData() error {
+	return nil
+}
+
+// CheckStateChange implements statemachine.StateMachineInterface, the
+// transition table already limits the allowed changes
+func (b *Breaker) CheckStateChange(stateNow, newState statemachine.State) (bool, error) {
+	return true, nil
+}
+
+// breakerState implements statemachine.State for the three breaker states
+type breakerState struct {
+	name State
+	b    *Breaker
+}
+
+func (s *breakerState) GetName() string {
+	return string(s.name)
+}
+
+func (s *breakerState) StateIn() error {
+	s.b.enter(s.name)
+	return nil
+}
+
+func (s *breakerState) StateOut() error {
+	return nil
+}
@@ -0,0 +1,184 @@
+package circuitbreaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"lbox/pkg/clock"
+)
+
+var errBackend = errors.New("backend unavailable")
+
+func newTestBreaker(settings Settings) (*Breaker, *clock.Fake, *[]Change) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	settings.Clock = fake
+	b := New(settings)
+
+	changes := &[]Change{}
+	b.OnStateChange(func(c Change) {
+		*changes = append(*changes, c)
+		// Listeners may call back into the breaker
+		_ = b.State()
+	})
+	return b, fake, changes
+}
+
+func run(b *Breaker, n int, err error) {
+	for i := 0; i < n; i++ {
+		_ = b.Execute(func() error { return err })
+	}
+}
+
+func TestBreaker_TripsOnFailureRate(t *testing.T) {
+	b, _, changes := newTestBreaker(Settings{MinRequests: 4, FailureRate: 0.5})
+
+	run(b, 2, nil)
+	run(b, 1, errBackend)
+	if b.State() != StateClosed {
+		t.Fatal("Breaker should stay closed below MinRequests")
+	}
+
+	run(b, 1, errBackend)
+	if b.State() != StateOpen {
+		t.Fatalf("Expected breaker to open, got %s", b.State())
+	}
+	if err := b.Execute(func() error { return nil }); !errors.Is(err, ErrOpen) {
+		t.Errorf("Expected ErrOpen, got %v", err)
+	}
+
+	if len(*changes) != 1 || (*changes)[0].From != StateClosed || (*changes)[0].To != StateOpen {
+		t.Fatalf("Unexpected changes: %+v", *changes)
+	}
+	if (*changes)[0].Reason == "" {
+		t.Error("Expected change to carry a reason")
+	}
+}
+
+func TestBreaker_WindowExpires(t *testing.T) {
+	b, fake, _ := newTestBreaker(Settings{Window: 10 * time.Second, MinRequests: 4})
+
+	run(b, 3, errBackend)
+	fake.Advance(11 * time.Second)
+	run(b, 1, errBackend)
+
+	if b.State() != StateClosed {
+		t.Errorf("Expired failures should not count, got %s", b.State())
+	}
+}
+
+func TestBreaker_HalfOpenProbes(t *testing.T) {
+	b, fake, changes := newTestBreaker(Settings{
+		MinRequests:       1,
+		OpenTimeout:       time.Minute,
+		HalfOpenProbes:    2,
+		HalfOpenSuccesses: 2,
+	})
+
+	run(b, 1, errBackend)
+	fake.Advance(59 * time.Second)
+	if b.State() != StateOpen {
+		t.Fatal("Breaker should stay open until the timeout elapses")
+	}
+
+	fake.Advance(time.Second)
+	if b.State() != StateHalfOpen {
+		t.Fatalf("Expected half-open after timeout, got %s", b.State())
+	}
+
+	done1, err := b.Allow()
+	if err != nil {
+		t.Fatalf("First probe rejected: %v", err)
+	}
+	done2, err := b.Allow()
+	if err != nil {
+		t.Fatalf("Second probe rejected: %v", err)
+	}
+	if _, err := b.Allow(); !errors.Is(err, ErrTooManyProbes) {
+		t.Errorf("Expected ErrTooManyProbes, got %v", err)
+	}
+
+	done1(nil)
+	done1(nil) // reporting twice is ignored
+	if b.State() != StateHalfOpen {
+		t.Fatal("One success should not close the breaker")
+	}
+	done2(nil)
+	if b.State() != StateClosed {
+		t.Fatalf("Expected breaker to close, got %s", b.State())
+	}
+
+	want := []State{StateOpen, StateHalfOpen, StateClosed}
+	if len(*changes) != len(want) {
+		t.Fatalf("Expected %d changes, got %+v", len(want), *changes)
+	}
+	for i, c := range *changes {
+		if c.To != want[i] {
+			t.Errorf("Change %d: expected %s, got %s", i, want[i], c.To)
+		}
+	}
+}
+
+func TestBreaker_FailedProbeReopens(t *testing.T) {
+	b, fake, _ := newTestBreaker(Settings{MinRequests: 1, OpenTimeout: time.Second})
+
+	run(b, 1, errBackend)
+	fake.Advance(time.Second)
+	run(b, 1, errBackend)
+
+	if b.State() != StateOpen {
+		t.Fatalf("Expected failed probe to reopen, got %s", b.State())
+	}
+
+	// A fresh timeout applies after reopening
+	fake.Advance(500 * time.Millisecond)
+	if b.State() != StateOpen {
+		t.Error("Breaker should wait a full timeout after reopening")
+	}
+}
+
+func TestBreaker_StaleResultsIgnored(t *testing.T) {
+	b, fake, _ := newTestBreaker(Settings{MinRequests: 1, OpenTimeout: time.Second})
+
+	slow, err := b.Allow()
+	if err != nil {
+		t.Fatalf("Allow failed: %v", err)
+	}
+	run(b, 1, errBackend)
+	fake.Advance(time.Second)
+	if b.State() != StateHalfOpen {
+		t.Fatalf("Expected half-open, got %s", b.State())
+	}
+
+	// The slow call was admitted while closed and must not count as a probe
+	slow(errBackend)
+	if b.State() != StateHalfOpen {
+		t.Errorf("Stale failure changed state to %s", b.State())
+	}
+}
+
+func TestBreaker_IsFailure(t *testing.T) {
+	b, _, _ := newTestBreaker(Settings{
+		MinRequests: 1,
+		IsFailure:   func(err error) bool { return errors.Is(err, errBackend) },
+	})
+
+	run(b, 3, errors.New("not found"))
+	if b.State() != StateClosed {
+		t.Errorf("Errors not classified as failures should not trip, got %s", b.State())
+	}
+}
+
+// nowClock is a caller-supplied clock implementing only Now
+type nowClock struct{ now time.Time }
+
+func (c nowClock) Now() time.Time {
+	return c.now
+}
+
+func TestBreaker_CustomClock(t *testing.T) {
+	b := New(Settings{Clock: nowClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}})
+	if err := b.Execute(func() error { return nil }); err != nil {
+		t.Errorf("Expected a Now-only clock to be accepted, got %v", err)
+	}
+}
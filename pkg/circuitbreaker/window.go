@@ -0,0 +1,60 @@
+package circuitbreaker
+
+import "time"
+
+// bucket counts the calls of one slot of the rolling window
+type bucket struct {
+	slot     int64
+	total    int
+	failures int
+}
+
+// window is a rolling count of calls split into fixed-width buckets
+type window struct {
+	width   time.Duration
+	buckets []bucket
+}
+
+func newWindow(size time.Duration, buckets int) *window {
+	width := size / time.Duration(buckets)
+	if width <= 0 {
+		width = 1
+	}
+	return &window{
+		width:   width,
+		buckets: make([]bucket, buckets),
+	}
+}
+
+// record adds a call made at now
+func (w *window) record(now time.Time, failed bool) {
+	slot := now.UnixNano() / int64(w.width)
+	b := &w.buckets[slot%int64(len(w.buckets))]
+	if b.slot != slot {
+		*b = bucket{slot: slot}
+	}
+	b.total++
+	if failed {
+		b.failures++
+	}
+}
+
+// totals sums the calls of the buckets still inside the window at now
+func (w *window) totals(now time.Time) (total, failures int) {
+	slot := now.UnixNano() / int64(w.width)
+	oldest := slot - int64(len(w.buckets)) + 1
+	for _, b := range w.buckets {
+		if b.slot >= oldest && b.slot <= slot {
+			total += b.total
+			failures += b.failures
+		}
+	}
+	return total, failures
+}
+
+// reset forgets every recorded call
+func (w *window) reset() {
+	for i := range w.buckets {
+		w.buckets[i] = bucket{}
+	}
+}
@@ -0,0 +1,157 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock tells the time, letting time-based components be tested without
+// waiting for real time to pass
+type Clock interface {
+	Now() time.Time
+}
+
+// TimerClock is a Clock that also creates timers, for components that wait
+// for a point in time
+type TimerClock interface {
+	Clock
+	// NewTimer creates a timer delivering the time on its channel once d has
+	// elapsed
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a single-shot timer created by a TimerClock
+type Timer interface {
+	// C returns the channel the time is delivered on
+	C() <-chan time.Time
+	// Stop prevents the timer from firing, reporting false when it already
+	// fired or was stopped
+	Stop() bool
+}
+
+// Real is the Clock backed by the system time
+type Real struct{}
+
+var (
+	_ TimerClock = Real{}
+	_ TimerClock = (*Fake)(nil)
+)
+
+// New returns the system clock
+func New() Clock {
+	return Real{}
+}
+
+// Now returns the current system time
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+// NewTimer returns a timer backed by time.Timer
+func (Real) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+type realTimer struct {
+	t *time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.t.C
+}
+
+func (t realTimer) Stop() bool {
+	return t.t.Stop()
+}
+
+// Fake is a manually advanced TimerClock for deterministic tests. Its timers
+// fire when Advance moves the time past their deadline
+type Fake struct {
+	now    time.Time
+	timers []*fakeTimer
+	mu     sync.Mutex
+	cond   *sync.Cond
+}
+
+// NewFake creates a fake clock showing start
+func NewFake(start time.Time) *Fake {
+	f := &Fake{now: start}
+	f.cond = sync.NewCond(&f.mu)
+	return f
+}
+
+// Now returns the fake time
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Advance moves the fake time forward by d and fires the timers that are due,
+// in deadline order
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+
+	sort.SliceStable(f.timers, func(i, j int) bool {
+		return f.timers[i].deadline.Before(f.timers[j].deadline)
+	})
+	pending := f.timers[:0]
+	for _, t := range f.timers {
+		if t.deadline.After(f.now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.c <- t.deadline
+	}
+	f.timers = pending
+}
+
+// NewTimer creates a timer firing once the fake time reaches now + d
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t := &fakeTimer{f: f, deadline: f.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.c <- f.now
+		return t
+	}
+	f.timers = append(f.timers, t)
+	f.cond.Broadcast()
+	return t
+}
+
+// BlockUntil waits until at least n timers are pending, so a test can advance
+// the time once the code under test is waiting
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.timers) < n {
+		f.cond.Wait()
+	}
+}
+
+type fakeTimer struct {
+	f        *Fake
+	deadline time.Time
+	c        chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	for i, pending := range t.f.timers {
+		if pending == t {
+			t.f.timers = append(t.f.timers[:i], t.f.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
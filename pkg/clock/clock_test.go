@@ -0,0 +1,76 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFake_Advance(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := NewFake(start)
+
+	if !f.Now().Equal(start) {
+		t.Errorf("Expected %v, got %v", start, f.Now())
+	}
+
+	f.Advance(90 * time.Second)
+	if want := start.Add(90 * time.Second); !f.Now().Equal(want) {
+		t.Errorf("Expected %v, got %v", want, f.Now())
+	}
+}
+
+func TestReal_Now(t *testing.T) {
+	before := time.Now()
+	now := New().Now()
+	if now.Before(before) {
+		t.Errorf("Real clock went backwards: %v before %v", now, before)
+	}
+}
+
+func TestFake_Timers(t *testing.T) {
+	f := NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	early := f.NewTimer(time.Second)
+	late := f.NewTimer(time.Minute)
+	stopped := f.NewTimer(time.Second)
+
+	if !stopped.Stop() || stopped.Stop() {
+		t.Error("Expected Stop to report true only for a pending timer")
+	}
+	f.BlockUntil(2)
+
+	f.Advance(30 * time.Second)
+	select {
+	case at := <-early.C():
+		if want := f.Now().Add(-29 * time.Second); !at.Equal(want) {
+			t.Errorf("Expected timer to deliver its deadline %v, got %v", want, at)
+		}
+	default:
+		t.Error("Expected due timer to fire")
+	}
+	select {
+	case <-late.C():
+		t.Error("Timer fired before its deadline")
+	case <-stopped.C():
+		t.Error("Stopped timer fired")
+	default:
+	}
+
+	f.Advance(time.Minute)
+	select {
+	case <-late.C():
+	default:
+		t.Error("Expected late timer to fire")
+	}
+	if late.Stop() {
+		t.Error("Stop should report false after the timer fired")
+	}
+}
+
+func TestReal_Timer(t *testing.T) {
+	timer := Real{}.NewTimer(time.Millisecond)
+	select {
+	case <-timer.C():
+	case <-time.After(time.Second):
+		t.Fatal("Real timer did not fire")
+	}
+}
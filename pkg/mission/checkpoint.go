@@ -0,0 +1,278 @@
+package mission
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// StepInfo describes the execution of a step. Steps implementing
+// ContextStepDisposer read it from their context with StepInfoFrom
+type StepInfo struct {
+	MissionID string
+	Step      string
+	// Attempt counts the retries of the current execution, starting at 1
+	Attempt int
+	// Execution counts how often the step was entered by the mission,
+	// starting at 1. It is preserved across Restore
+	Execution int
+	// Resumed is set when the step was running while the checkpoint was
+	// saved, so it may already have applied some of its side effects
+	Resumed bool
+	// IdempotencyKey is stable across retries and resumes of one execution and
+	// can be handed to external systems to deduplicate side effects
+	IdempotencyKey string
+}
+
+type stepInfoKey struct{}
+
+// StepInfoFrom returns the StepInfo of the step running with ctx
+func StepInfoFrom(ctx context.Context) (StepInfo, bool) {
+	info, ok := ctx.Value(stepInfoKey{}).(StepInfo)
+	return info, ok
+}
+
+// WithCheckpoint saves the progress of the mission under id into store before
+// and after every step and when the mission completes. A failing save fails
+// the mission
+func WithCheckpoint(store Store, id string) Option {
+	return func(m *Mission) {
+		m.store = store
+		m.id = id
+	}
+}
+
+// ID returns the checkpoint ID of the mission, empty without WithCheckpoint
+func (m *Mission) ID() string {
+	return m.id
+}
+
+// Restore loads the checkpoint saved under id and creates a mission over
+// stepList that continues from it when started. Completed steps are not run
+// again; a step that was running when the checkpoint was saved runs again
+// with StepInfo.Resumed set. Mission data is restored from its JSON form and
+// decoded on first access through a Key
+func Restore(ctx context.Context, store Store, id string, stepList []StepDisposer, opts ...Option) (*Mission, error) {
+	cp, err := store.Load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if cp.Outcome != OutcomeUnfinished {
+		return nil, fmt.Errorf("mission %s already finished: %s", id, cp.Outcome)
+	}
+
+	m, err := New(stepList, append(opts, WithCheckpoint(store, id))...)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkRestorable(cp); err != nil {
+		return nil, fmt.Errorf("checkpoint of mission %s: %w", id, err)
+	}
+	for name, raw := range cp.Data {
+		m.data.Set(name, raw)
+	}
+	m.restored = &cp
+	return m, nil
+}
+
+// checkRestorable rejects checkpoints referring to steps the mission lacks
+func (m *Mission) checkRestorable(cp Checkpoint) error {
+	names := append(append([]string(nil), cp.Running...), cp.History...)
+	for _, entry := range cp.Path {
+		names = append(names, entry.Name)
+	}
+	for _, pending := range cp.Pending {
+		if _, ok := parseCommandKind(pending.Kind); !ok {
+			return fmt.Errorf("unknown command: %s", pending.Kind)
+		}
+		if pending.Target != "" {
+			names = append(names, pending.Target)
+		}
+	}
+	if cp.Current != "" {
+		names = append(names, cp.Current)
+	}
+	for _, name := range names {
+		if m.indexOf(name) < 0 {
+			return fmt.Errorf("unknown step: %s", name)
+		}
+	}
+	return nil
+}
+
+// restore applies a loaded checkpoint to a run being started
+// Note: This method assumes the caller holds the lock
+func (m *Mission) restore(cp *Checkpoint) {
+	m.resumed = make(map[string]bool, len(cp.Running))
+	for _, name := range cp.Running {
+		m.resumed[name] = true
+	}
+
+	for name, payloads := range cp.Signals {
+		for _, raw := range payloads {
+			m.signals[name] = append(m.signals[name], raw)
+		}
+	}
+	for step, deadline := range cp.Deadlines {
+		m.waitDeadlines[step] = deadline
+	}
+
+	m.completedSteps = make(map[string]bool, len(cp.Path))
+	for _, entry := range cp.Path {
+		record := StepRecord{Name: entry.Name, Attempts: entry.Attempts}
+		if entry.Error != "" {
+			record.Err = errors.New(entry.Error)
+			m.stepErrors = append(m.stepErrors, &StepError{Step: entry.Name, Attempts: entry.Attempts, Err: record.Err})
+		} else {
+			m.completedSteps[entry.Name] = true
+		}
+		m.path = append(m.path, record)
+	}
+
+	if m.graph != nil {
+		return
+	}
+	if cp.Current != "" {
+		m.stepNow = m.stepList[m.indexOf(cp.Current)]
+	}
+	m.history = append(m.history[:0], cp.History...)
+	m.taskList = m.taskList[:0]
+	if len(cp.Running) > 0 {
+		// The interrupted step is the current one and was already entered
+		m.taskList = append(m.taskList, command{kind: cmdRetry})
+	}
+	for _, pending := range cp.Pending {
+		kind, _ := parseCommandKind(pending.Kind)
+		m.taskList = append(m.taskList, command{kind: kind, target: pending.Target})
+	}
+}
+
+// stepInfo describes the next execution of the named step
+// Note: This method assumes the caller holds the lock
+func (m *Mission) stepInfo(name string) StepInfo {
+	info := StepInfo{MissionID: m.id, Step: name, Execution: 1, Resumed: m.resumed[name]}
+	delete(m.resumed, name)
+	for _, record := range m.path {
+		if record.Name == name {
+			info.Execution++
+		}
+	}
+	info.IdempotencyKey = fmt.Sprintf("%s/%s/%d", m.id, name, info.Execution)
+	return info
+}
+
+// checkpoint saves the progress of the mission with running as the steps in
+// flight and reports whether it succeeded. A failing save fails the mission
+func (m *Mission) checkpoint(running ...string) bool {
+	if m.store == nil {
+		return true
+	}
+
+	m.saveMu.Lock()
+	defer m.saveMu.Unlock()
+
+	m.mu.Lock()
+	m.lastRunning = running
+	ctx := context.WithoutCancel(m.ctx)
+	cp, err := m.snapshot(running)
+	m.mu.Unlock()
+
+	if err == nil {
+		err = m.store.Save(ctx, cp)
+	}
+	if err != nil {
+		m.mu.Lock()
+		if m.running {
+			m.finish(OutcomeFailed, fmt.Errorf("checkpoint: %w", err))
+		}
+		m.mu.Unlock()
+		return false
+	}
+	return true
+}
+
+// snapshot builds the checkpoint of the current progress
+// Note: This method assumes the caller holds the lock
+func (m *Mission) snapshot(running []string) (Checkpoint, error) {
+	cp := Checkpoint{
+		ID:        m.id,
+		Outcome:   m.outcome,
+		Running:   running,
+		Current:   m.currentName(),
+		History:   append([]string(nil), m.history...),
+		UpdatedAt: m.clock.Now(),
+	}
+	for _, record := range m.path {
+		entry := PathEntry{Name: record.Name, Attempts: record.Attempts}
+		if record.Err != nil {
+			entry.Error = record.Err.Error()
+		}
+		cp.Path = append(cp.Path, entry)
+	}
+	for _, task := range m.taskList {
+		cp.Pending = append(cp.Pending, PendingCommand{Kind: task.kind.String(), Target: task.target})
+	}
+
+	for name, payloads := range m.signals {
+		for _, payload := range payloads {
+			raw, err := json.Marshal(payload)
+			if err != nil {
+				return cp, fmt.Errorf("signal %s: %w", name, err)
+			}
+			if cp.Signals == nil {
+				cp.Signals = make(map[string][]json.RawMessage)
+			}
+			cp.Signals[name] = append(cp.Signals[name], raw)
+		}
+	}
+	for step, deadline := range m.waitDeadlines {
+		if cp.Deadlines == nil {
+			cp.Deadlines = make(map[string]time.Time)
+		}
+		cp.Deadlines[step] = deadline
+	}
+
+	values := m.data.Snapshot()
+	if len(values) > 0 {
+		cp.Data = make(map[string]json.RawMessage, len(values))
+	}
+	for name, value := range values {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return cp, fmt.Errorf("data %s: %w", name, err)
+		}
+		cp.Data[name] = raw
+	}
+	return cp, nil
+}
+
+// String returns the persisted name of the command kind
+func (k commandKind) String() string {
+	switch k {
+	case cmdStart:
+		return "start"
+	case cmdNext:
+		return "next"
+	case cmdJump:
+		return "jump"
+	case cmdBack:
+		return "back"
+	case cmdRetry:
+		return "retry"
+	case cmdRestart:
+		return "restart"
+	default:
+		return "unknown"
+	}
+}
+
+func parseCommandKind(name string) (commandKind, bool) {
+	for _, kind := range []commandKind{cmdStart, cmdNext, cmdJump, cmdBack, cmdRetry, cmdRestart} {
+		if kind.String() == name {
+			return kind, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,209 @@
+package mission
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// infoStep records the StepInfo of each of its executions
+type infoStep struct {
+	name  string
+	fn    func(ctx context.Context, m *Mission) error
+	infos []StepInfo
+	mu    sync.Mutex
+}
+
+func (s *infoStep) StepName() string {
+	return s.name
+}
+
+func (s *infoStep) Dispose(m *Mission, tags []interface{}) error {
+	return s.DisposeContext(context.Background(), m, tags)
+}
+
+func (s *infoStep) DisposeContext(ctx context.Context, m *Mission, _ []interface{}) error {
+	info, _ := StepInfoFrom(ctx)
+	s.mu.Lock()
+	s.infos = append(s.infos, info)
+	s.mu.Unlock()
+	if s.fn != nil {
+		return s.fn(ctx, m)
+	}
+	return nil
+}
+
+func (s *infoStep) Infos() []StepInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]StepInfo(nil), s.infos...)
+}
+
+// crash copies the checkpoint saved so far into snapshot and stops m, which
+// leaves snapshot as it would be after the process died at this point
+func crash(ctx context.Context, m *Mission, store, snapshot Store) error {
+	cp, err := store.Load(ctx, m.ID())
+	if err != nil {
+		return err
+	}
+	if err := snapshot.Save(ctx, cp); err != nil {
+		return err
+	}
+	m.Stop()
+	return nil
+}
+
+func TestMission_ResumeLinear(t *testing.T) {
+	store, snapshot := NewMemoryStore(), NewMemoryStore()
+	ctx := context.Background()
+
+	crashed := false
+	newSteps := func() (*infoStep, *infoStep, *infoStep) {
+		a := &infoStep{name: "a", fn: func(_ context.Context, m *Mission) error {
+			keyCount.Set(m.Data(), 42)
+			return nil
+		}}
+		b := &infoStep{name: "b", fn: func(ctx context.Context, m *Mission) error {
+			if !crashed {
+				crashed = true
+				return crash(ctx, m, store, snapshot)
+			}
+			return nil
+		}}
+		c := &infoStep{name: "c"}
+		return a, b, c
+	}
+
+	a, b, c := newSteps()
+	m := mustNew(t, []StepDisposer{a, b, c}, WithAutoAdvance(), WithCheckpoint(store, "order-1"))
+	m.Start()
+	if result := waitResult(t, m); result.Outcome != OutcomeStopped {
+		t.Fatalf("Expected first run to stop, got %s", result.Outcome)
+	}
+	firstKey := b.Infos()[0].IdempotencyKey
+
+	a, b, c = newSteps()
+	resumed, err := Restore(ctx, snapshot, "order-1", []StepDisposer{a, b, c}, WithAutoAdvance())
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	resumed.Start()
+	result := waitResult(t, resumed)
+
+	if result.Outcome != OutcomeSucceeded {
+		t.Fatalf("Expected resumed mission to succeed, got %s (%v)", result.Outcome, result.Err)
+	}
+	if got := result.StepNames(); len(got) != 3 || got[0] != "a" || got[1] != "b" || got[2] != "c" {
+		t.Errorf("Expected path [a b c], got %v", got)
+	}
+	if len(a.Infos()) != 0 {
+		t.Error("Completed step should not run again")
+	}
+	infos := b.Infos()
+	if len(infos) != 1 || !infos[0].Resumed || infos[0].IdempotencyKey != firstKey {
+		t.Errorf("Expected interrupted step to rerun as resumed with key %s, got %+v", firstKey, infos)
+	}
+	if infos := c.Infos(); len(infos) != 1 || infos[0].Resumed || infos[0].IdempotencyKey != "order-1/c/1" {
+		t.Errorf("Expected fresh execution of c, got %+v", infos)
+	}
+	if got, ok := keyCount.Get(resumed.Data()); !ok || got != 42 {
+		t.Errorf("Expected restored data 42, got %d (%v)", got, ok)
+	}
+
+	cp, err := snapshot.Load(ctx, "order-1")
+	if err != nil || cp.Outcome != OutcomeSucceeded {
+		t.Errorf("Expected final checkpoint to record success, got %s (%v)", cp.Outcome, err)
+	}
+	if _, err := Restore(ctx, snapshot, "order-1", []StepDisposer{a, b, c}); err == nil {
+		t.Error("Expected Restore of a finished mission to fail")
+	}
+}
+
+func TestMission_ResumeDAG(t *testing.T) {
+	store, snapshot := NewMemoryStore(), NewMemoryStore()
+	ctx := context.Background()
+
+	crashed := false
+	newSteps := func() []*infoStep {
+		return []*infoStep{
+			{name: "fetch"},
+			{name: "resize", fn: func(ctx context.Context, m *Mission) error {
+				if !crashed {
+					crashed = true
+					return crash(ctx, m, store, snapshot)
+				}
+				return nil
+			}},
+			{name: "thumbnail"},
+		}
+	}
+	disposers := func(steps []*infoStep) []StepDisposer {
+		return []StepDisposer{steps[0], steps[1], steps[2]}
+	}
+	opts := []Option{
+		WithDependencies("resize", "fetch"),
+		WithDependencies("thumbnail", "resize"),
+	}
+
+	m := mustNew(t, disposers(newSteps()), append(opts, WithCheckpoint(store, "image"))...)
+	m.Start()
+	waitResult(t, m)
+
+	steps := newSteps()
+	resumed, err := Restore(ctx, snapshot, "image", disposers(steps), opts...)
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	resumed.Start()
+	result := waitResult(t, resumed)
+
+	if result.Outcome != OutcomeSucceeded {
+		t.Fatalf("Expected resumed DAG to succeed, got %s (%v)", result.Outcome, result.Err)
+	}
+	if len(steps[0].Infos()) != 0 {
+		t.Error("Completed DAG step should not run again")
+	}
+	if infos := steps[1].Infos(); len(infos) != 1 || !infos[0].Resumed {
+		t.Errorf("Expected interrupted DAG step to rerun as resumed, got %+v", infos)
+	}
+	if len(steps[2].Infos()) != 1 {
+		t.Error("Expected dependent step to run after resume")
+	}
+}
+
+type failingStore struct {
+	*MemoryStore
+}
+
+func (s failingStore) Save(context.Context, Checkpoint) error {
+	return errors.New("disk full")
+}
+
+func TestMission_CheckpointFailure(t *testing.T) {
+	step := &infoStep{name: "a"}
+	m := mustNew(t, []StepDisposer{step}, WithCheckpoint(failingStore{NewMemoryStore()}, "x"))
+	m.Start()
+	result := waitResult(t, m)
+
+	if result.Outcome != OutcomeFailed || result.Err == nil {
+		t.Fatalf("Expected failing store to fail the mission, got %s", result.Outcome)
+	}
+	if len(step.Infos()) != 0 {
+		t.Error("Step should not run when its checkpoint cannot be saved")
+	}
+}
+
+func TestRestore_Validation(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	if _, err := Restore(ctx, store, "missing", []StepDisposer{NewMockStep("a")}); !errors.Is(err, ErrCheckpointNotFound) {
+		t.Errorf("Expected ErrCheckpointNotFound, got %v", err)
+	}
+
+	_ = store.Save(ctx, Checkpoint{ID: "renamed", Path: []PathEntry{{Name: "old"}}})
+	if _, err := Restore(ctx, store, "renamed", []StepDisposer{NewMockStep("a")}); err == nil {
+		t.Error("Expected checkpoint with unknown steps to be rejected")
+	}
+}
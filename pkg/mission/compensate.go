@@ -0,0 +1,52 @@
+package mission
+
+import "context"
+
+// Compensator is implemented by steps that can undo their work. With
+// WithRollback, Compensate is called for every successful execution of the
+// step when the mission fails or is stopped, in reverse order of execution
+type Compensator interface {
+	Compensate(ctx context.Context, mission *Mission) error
+}
+
+// WithRollback enables saga-style compensation of completed steps when the
+// mission fails or is stopped
+func WithRollback() Option {
+	return func(m *Mission) {
+		m.rollback = true
+	}
+}
+
+// compensate undoes the successful executions of path in reverse order. The
+// context keeps the values of the run but not its cancellation
+func (m *Mission) compensate(ctx context.Context, path []StepRecord) {
+	for i := len(path) - 1; i >= 0; i-- {
+		record := path[i]
+		if record.Err != nil {
+			continue
+		}
+		step := m.stepList[m.indexOf(record.Name)]
+		c, ok := step.(Compensator)
+		if !ok {
+			continue
+		}
+
+		err := safeCompensate(ctx, m, c)
+		m.mu.Lock()
+		m.compensated = append(m.compensated, record.Name)
+		if err != nil {
+			m.compensationErrors = append(m.compensationErrors, &StepError{Step: record.Name, Attempts: 1, Err: err})
+		}
+		m.mu.Unlock()
+	}
+}
+
+// safeCompensate calls Compensate, turning a panic into a PanicError
+func safeCompensate(ctx context.Context, m *Mission, c Compensator) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r}
+		}
+	}()
+	return c.Compensate(ctx, m)
+}
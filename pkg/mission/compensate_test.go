@@ -0,0 +1,100 @@
+package mission
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// sagaStep succeeds or fails and records its compensation
+type sagaStep struct {
+	name          string
+	fail          bool
+	compensateErr error
+	log           *sagaLog
+}
+
+type sagaLog struct {
+	mu          sync.Mutex
+	compensated []string
+	ctxErr      error
+}
+
+func (s *sagaStep) StepName() string {
+	return s.name
+}
+
+func (s *sagaStep) Dispose(m *Mission, _ []interface{}) error {
+	if s.fail {
+		return errStep
+	}
+	return nil
+}
+
+func (s *sagaStep) Compensate(ctx context.Context, m *Mission) error {
+	s.log.mu.Lock()
+	defer s.log.mu.Unlock()
+	s.log.compensated = append(s.log.compensated, s.name)
+	s.log.ctxErr = ctx.Err()
+	return s.compensateErr
+}
+
+func TestMission_RollbackOnFailure(t *testing.T) {
+	log := &sagaLog{}
+	steps := []StepDisposer{
+		&sagaStep{name: "reserve", log: log},
+		NewMockStep("plain"),
+		&sagaStep{name: "charge", log: log, compensateErr: errors.New("refund failed")},
+		&sagaStep{name: "ship", log: log},
+		&sagaStep{name: "notify", fail: true, log: log},
+	}
+
+	m := mustNew(t, steps, WithAutoAdvance(), WithRollback())
+	m.Start()
+	result := waitResult(t, m)
+
+	if result.Outcome != OutcomeFailed {
+		t.Fatalf("Expected failure, got %s", result.Outcome)
+	}
+	if got := strings.Join(result.Compensated, ","); got != "ship,charge,reserve" {
+		t.Errorf("Expected reverse compensation, got %s", got)
+	}
+	if log.ctxErr != nil {
+		t.Errorf("Compensation context should not be cancelled, got %v", log.ctxErr)
+	}
+	if len(result.CompensationErrors) != 1 || result.CompensationErrors[0].Step != "charge" {
+		t.Errorf("Expected charge compensation error, got %v", result.CompensationErrors)
+	}
+	if errors.Is(result.Err, result.CompensationErrors[0]) {
+		t.Error("Compensation errors should be recorded separately from the mission error")
+	}
+}
+
+func TestMission_RollbackOnStop(t *testing.T) {
+	log := &sagaLog{}
+	blocker := NewContextStep("wait")
+	steps := []StepDisposer{&sagaStep{name: "reserve", log: log}, blocker}
+
+	m := mustNew(t, steps, WithAutoAdvance(), WithRollback())
+	m.Start()
+	<-blocker.started
+	m.Stop()
+	result := waitResult(t, m)
+
+	if result.Outcome != OutcomeStopped || strings.Join(result.Compensated, ",") != "reserve" {
+		t.Errorf("Expected reserve to be compensated on stop, got %s %v", result.Outcome, result.Compensated)
+	}
+}
+
+func TestMission_NoRollbackOnSuccess(t *testing.T) {
+	log := &sagaLog{}
+	m := mustNew(t, []StepDisposer{&sagaStep{name: "reserve", log: log}}, WithAutoAdvance(), WithRollback())
+	m.Start()
+	result := waitResult(t, m)
+
+	if result.Outcome != OutcomeSucceeded || len(result.Compensated) != 0 {
+		t.Errorf("Successful missions should not be compensated, got %v", result.Compensated)
+	}
+}
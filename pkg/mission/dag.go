@@ -0,0 +1,259 @@
+package mission
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// graph holds the step dependencies of a DAG mission
+type graph struct {
+	deps map[string][]string // step -> steps it depends on
+}
+
+// WithDependencies declares that step only runs after all of deps succeeded.
+// Declaring any dependency turns the mission into a DAG mission: steps run as
+// soon as their dependencies are done, concurrently when independent, and
+// GoNext and Jump are not used. Steps without declared dependencies are roots
+func WithDependencies(step string, deps ...string) Option {
+	return func(m *Mission) {
+		if m.graph == nil {
+			m.graph = &graph{deps: make(map[string][]string)}
+		}
+		m.graph.deps[step] = append(m.graph.deps[step], deps...)
+	}
+}
+
+// WithMaxParallel bounds the number of steps of a DAG mission running at the
+// same time, zero means no limit
+func WithMaxParallel(n int) Option {
+	return func(m *Mission) {
+		m.maxParallel = n
+	}
+}
+
+// WithContinueOnError keeps running the independent branches of a DAG
+// mission after a step failed. By default the first failure cancels the run
+func WithContinueOnError() Option {
+	return func(m *Mission) {
+		m.continueOnError = true
+	}
+}
+
+// validate rejects unknown steps and cycles
+func (g *graph) validate(steps []StepDisposer) error {
+	known := make(map[string]bool, len(steps))
+	for _, step := range steps {
+		known[step.StepName()] = true
+	}
+	for step, deps := range g.deps {
+		if !known[step] {
+			return fmt.Errorf("dependencies declared for unknown step: %s", step)
+		}
+		for _, dep := range deps {
+			if !known[dep] {
+				return fmt.Errorf("step %s depends on unknown step: %s", step, dep)
+			}
+		}
+	}
+
+	if cycle := g.findCycle(steps); cycle != nil {
+		return fmt.Errorf("dependency cycle: %s", strings.Join(cycle, " -> "))
+	}
+	return nil
+}
+
+// findCycle returns the steps of a dependency cycle, or nil
+func (g *graph) findCycle(steps []StepDisposer) []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(steps))
+	var stack []string
+
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		state[name] = visiting
+		stack = append(stack, name)
+		for _, dep := range g.deps[name] {
+			switch state[dep] {
+			case visiting:
+				for i, s := range stack {
+					if s == dep {
+						return append(append([]string(nil), stack[i:]...), dep)
+					}
+				}
+			case unvisited:
+				if cycle := visit(dep); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = visited
+		return nil
+	}
+
+	for _, step := range steps {
+		if state[step.StepName()] == unvisited {
+			if cycle := visit(step.StepName()); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// dagResult reports a finished step to the DAG scheduler
+type dagResult struct {
+	step    StepDisposer
+	record  StepRecord
+	handled bool // failed but the Skip policy lets dependents run
+}
+
+// runDAG is the executor of DAG missions. It starts every step whose
+// dependencies are done, up to maxParallel at a time, and waits for running
+// steps to return before completing
+func (m *Mission) runDAG(ctx context.Context) {
+	defer m.complete()
+
+	// Steps completed before a Restore count as done dependencies
+	m.mu.Lock()
+	completed := m.completedSteps
+	m.mu.Unlock()
+
+	pending := make(map[string]int, len(m.stepList))
+	dependents := make(map[string][]StepDisposer)
+	var ready []StepDisposer
+	for _, step := range m.stepList {
+		if completed[step.StepName()] {
+			continue
+		}
+		for _, dep := range m.graph.deps[step.StepName()] {
+			if !completed[dep] {
+				pending[step.StepName()]++
+				dependents[dep] = append(dependents[dep], step)
+			}
+		}
+		if pending[step.StepName()] == 0 {
+			ready = append(ready, step)
+		}
+	}
+
+	limit := m.maxParallel
+	if limit <= 0 {
+		limit = len(m.stepList)
+	}
+
+	results := make(chan dagResult)
+	ctxDone := ctx.Done()
+	expired, stop := m.deadlineTimer()
+	defer stop()
+	running := make(map[string]bool)
+	var firstErr error
+
+	for {
+		m.mu.Lock()
+		hold := m.paused || !m.running || ctx.Err() != nil || (firstErr != nil && !m.continueOnError)
+		m.mu.Unlock()
+
+		var launch []StepDisposer
+		for !hold && len(running) < limit && len(ready) > 0 {
+			step := ready[0]
+			ready = ready[1:]
+			running[step.StepName()] = true
+			launch = append(launch, step)
+		}
+
+		// The checkpoint lists the steps as running before they can apply
+		// side effects, so a restored mission reruns them as resumed
+		if len(launch) > 0 && !m.checkpoint(runningNames(running)...) {
+			for _, step := range launch {
+				delete(running, step.StepName())
+			}
+			launch = nil
+		}
+		for _, step := range launch {
+			go func() {
+				policy := m.policyFor(step.StepName())
+				record := m.attempt(ctx, step, policy)
+				skipped := policy.Action == ActionSkip && !errors.Is(record.Err, errDeadline)
+				results <- dagResult{
+					step:    step,
+					record:  record,
+					handled: record.Err != nil && skipped,
+				}
+			}()
+		}
+
+		if len(running) == 0 && (len(ready) == 0 || (hold && !m.IsPaused())) {
+			break
+		}
+
+		select {
+		case r := <-results:
+			delete(running, r.step.StepName())
+			m.mu.Lock()
+			m.path = append(m.path, r.record)
+			if r.record.Err != nil && ctx.Err() == nil {
+				stepErr := &StepError{Step: r.record.Name, Attempts: r.record.Attempts, Err: r.record.Err}
+				m.stepErrors = append(m.stepErrors, stepErr)
+				if !r.handled && firstErr == nil {
+					firstErr = stepErr
+				}
+				// The deadline ends the run even when errors do not
+				if !r.handled && (!m.continueOnError || errors.Is(r.record.Err, errDeadline)) {
+					m.cancel()
+				}
+			}
+			m.mu.Unlock()
+
+			if r.record.Err == nil || r.handled {
+				for _, next := range dependents[r.step.StepName()] {
+					pending[next.StepName()]--
+					if pending[next.StepName()] == 0 {
+						ready = append(ready, next)
+					}
+				}
+			}
+			m.checkpoint(runningNames(running)...)
+		case <-m.wake:
+		case <-expired:
+			// Running steps are bounded by the deadline themselves
+			expired = nil
+			if len(running) == 0 {
+				m.expire()
+			}
+		case <-ctxDone:
+			ctxDone = nil
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.running {
+		return
+	}
+	switch {
+	case firstErr != nil:
+		m.finish(OutcomeFailed, firstErr)
+	case ctx.Err() != nil:
+		m.finish(OutcomeStopped, ctx.Err())
+	default:
+		m.finish(OutcomeSucceeded, nil)
+	}
+}
+
+// runningNames lists the running steps of a DAG mission in a stable order
+func runningNames(running map[string]bool) []string {
+	names := make([]string, 0, len(running))
+	for name := range running {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,210 @@
+package mission
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// dagProbe records step order and the highest observed concurrency
+type dagProbe struct {
+	mu      sync.Mutex
+	order   []string
+	running int
+	peak    int
+}
+
+func (p *dagProbe) step(name string, delay time.Duration, err error) StepDisposer {
+	return &dagStep{name: name, probe: p, delay: delay, err: err}
+}
+
+func (p *dagProbe) Order() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.order...)
+}
+
+func (p *dagProbe) Peak() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.peak
+}
+
+type dagStep struct {
+	name  string
+	probe *dagProbe
+	delay time.Duration
+	err   error
+}
+
+func (s *dagStep) StepName() string {
+	return s.name
+}
+
+func (s *dagStep) Dispose(m *Mission, tags []interface{}) error {
+	return s.DisposeContext(context.Background(), m, tags)
+}
+
+func (s *dagStep) DisposeContext(ctx context.Context, _ *Mission, _ []interface{}) error {
+	s.probe.mu.Lock()
+	s.probe.running++
+	if s.probe.running > s.probe.peak {
+		s.probe.peak = s.probe.running
+	}
+	s.probe.mu.Unlock()
+
+	defer func() {
+		s.probe.mu.Lock()
+		s.probe.running--
+		s.probe.order = append(s.probe.order, s.name)
+		s.probe.mu.Unlock()
+	}()
+
+	select {
+	case <-time.After(s.delay):
+		return s.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func indexIn(order []string, name string) int {
+	for i, n := range order {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestMission_DAGParallel(t *testing.T) {
+	p := &dagProbe{}
+	steps := []StepDisposer{
+		p.step("build", 5*time.Millisecond, nil),
+		p.step("lint", 20*time.Millisecond, nil),
+		p.step("test", 20*time.Millisecond, nil),
+		p.step("deploy", time.Millisecond, nil),
+	}
+
+	m := mustNew(t, steps,
+		WithDependencies("test", "build"),
+		WithDependencies("deploy", "lint", "test"))
+	m.Start()
+	if err := m.GoNext(); !errors.Is(err, ErrNavigationUnsupported) {
+		t.Errorf("Expected ErrNavigationUnsupported, got %v", err)
+	}
+	result := waitResult(t, m)
+
+	if result.Outcome != OutcomeSucceeded {
+		t.Fatalf("Expected success, got %s: %v", result.Outcome, result.Err)
+	}
+	order := p.Order()
+	if len(order) != 4 || order[3] != "deploy" || indexIn(order, "build") > indexIn(order, "test") {
+		t.Errorf("Dependencies not respected: %v", order)
+	}
+	if p.Peak() < 2 {
+		t.Errorf("Independent steps should run concurrently, peak was %d", p.Peak())
+	}
+	if len(result.Path) != 4 {
+		t.Errorf("Expected 4 records in path, got %d", len(result.Path))
+	}
+}
+
+func TestMission_DAGMaxParallel(t *testing.T) {
+	p := &dagProbe{}
+	steps := []StepDisposer{
+		p.step("a", 5*time.Millisecond, nil),
+		p.step("b", 5*time.Millisecond, nil),
+		p.step("c", 5*time.Millisecond, nil),
+		p.step("d", 5*time.Millisecond, nil),
+	}
+
+	m := mustNew(t, steps, WithDependencies("a"), WithMaxParallel(2))
+	m.Start()
+	waitResult(t, m)
+
+	if p.Peak() != 2 {
+		t.Errorf("Expected peak concurrency 2, got %d", p.Peak())
+	}
+}
+
+func TestMission_DAGFailure(t *testing.T) {
+	tests := []struct {
+		name            string
+		opts            []Option
+		expectSlowRuns  bool
+		expectOutcome   Outcome
+		expectErrorStep string
+	}{
+		{name: "Fail fast", expectSlowRuns: false, expectOutcome: OutcomeFailed, expectErrorStep: "broken"},
+		{name: "Continue on error", opts: []Option{WithContinueOnError()}, expectSlowRuns: true, expectOutcome: OutcomeFailed, expectErrorStep: "broken"},
+		{name: "Skip policy", opts: []Option{WithStepPolicy("broken", Skip())}, expectSlowRuns: true, expectOutcome: OutcomeSucceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &dagProbe{}
+			steps := []StepDisposer{
+				p.step("broken", time.Millisecond, errStep),
+				p.step("after", time.Millisecond, nil),
+				p.step("slow", 20*time.Millisecond, nil),
+			}
+			opts := append([]Option{WithDependencies("after", "broken")}, tt.opts...)
+
+			m := mustNew(t, steps, opts...)
+			m.Start()
+			result := waitResult(t, m)
+
+			if result.Outcome != tt.expectOutcome {
+				t.Fatalf("Expected %s, got %s: %v", tt.expectOutcome, result.Outcome, result.Err)
+			}
+			var stepErr *StepError
+			if tt.expectErrorStep != "" && (!errors.As(result.Err, &stepErr) || stepErr.Step != tt.expectErrorStep) {
+				t.Errorf("Expected error of %s, got %v", tt.expectErrorStep, result.Err)
+			}
+
+			slowDone := false
+			for _, record := range result.Path {
+				if record.Name == "slow" && record.Err == nil {
+					slowDone = true
+				}
+			}
+			if slowDone != tt.expectSlowRuns {
+				t.Errorf("Expected slow branch completed=%v, path %+v", tt.expectSlowRuns, result.Path)
+			}
+
+			afterRan := indexIn(p.Order(), "after") >= 0
+			if afterRan != (tt.expectOutcome == OutcomeSucceeded) {
+				t.Errorf("Dependent of the failed step ran=%v", afterRan)
+			}
+		})
+	}
+}
+
+func TestNew_DAGValidation(t *testing.T) {
+	steps := []StepDisposer{NewMockStep("a"), NewMockStep("b"), NewMockStep("c")}
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		contains string
+	}{
+		{name: "Cycle", opts: []Option{WithDependencies("a", "c"), WithDependencies("b", "a"), WithDependencies("c", "b")}, contains: "cycle"},
+		{name: "Self dependency", opts: []Option{WithDependencies("a", "a")}, contains: "a -> a"},
+		{name: "Unknown dependency", opts: []Option{WithDependencies("a", "x")}, contains: "unknown step: x"},
+		{name: "Unknown step", opts: []Option{WithDependencies("x", "a")}, contains: "unknown step: x"},
+		{name: "Jump policy", opts: []Option{WithDependencies("b", "a"), WithStepPolicy("a", JumpTo("c"))}, contains: "not supported"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(steps, tt.opts...)
+			if err == nil || !strings.Contains(err.Error(), tt.contains) {
+				t.Errorf("Expected error containing %q, got %v", tt.contains, err)
+			}
+		})
+	}
+}
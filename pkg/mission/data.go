@@ -0,0 +1,129 @@
+package mission
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// Data is a concurrency-safe key/value store shared by the steps of a mission.
+// Typed access goes through Key
+type Data struct {
+	values map[string]interface{}
+	mu     sync.RWMutex
+}
+
+// NewData creates an empty store
+func NewData() *Data {
+	return &Data{values: make(map[string]interface{})}
+}
+
+// Get returns the raw value stored under name
+func (d *Data) Get(name string) (interface{}, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	value, ok := d.values[name]
+	return value, ok
+}
+
+// Set stores value under name
+func (d *Data) Set(name string, value interface{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.values[name] = value
+}
+
+// Delete removes the value stored under name
+func (d *Data) Delete(name string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.values, name)
+}
+
+// Snapshot returns a copy of all values
+func (d *Data) Snapshot() map[string]interface{} {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	snapshot := make(map[string]interface{}, len(d.values))
+	for name, value := range d.values {
+		snapshot[name] = value
+	}
+	return snapshot
+}
+
+// Key is a typed accessor for one value of a Data store
+type Key[T any] struct {
+	name string
+}
+
+// NewKey creates a key for values of type T stored under name
+func NewKey[T any](name string) Key[T] {
+	return Key[T]{name: name}
+}
+
+// Name returns the name the value is stored under
+func (k Key[T]) Name() string {
+	return k.name
+}
+
+// Get returns the value of k, reporting false when it is missing or holds
+// another type. Values restored from a checkpoint are decoded on first access
+func (k Key[T]) Get(d *Data) (T, bool) {
+	value, ok := d.Get(k.name)
+	typed, ok := k.cast(value, ok)
+	if ok {
+		if _, raw := value.(json.RawMessage); !raw {
+			return typed, true
+		}
+		d.Set(k.name, typed)
+	}
+	return typed, ok
+}
+
+// GetOr returns the value of k or def when it is missing
+func (k Key[T]) GetOr(d *Data, def T) T {
+	if value, ok := k.Get(d); ok {
+		return value
+	}
+	return def
+}
+
+// Set stores value under k
+func (k Key[T]) Set(d *Data, value T) {
+	d.Set(k.name, value)
+}
+
+// Output returns the value of k from the data of a mission result
+func (k Key[T]) Output(r Result) (T, bool) {
+	value, ok := r.Outputs[k.name]
+	return k.cast(value, ok)
+}
+
+func (k Key[T]) cast(value interface{}, ok bool) (T, bool) {
+	typed, isT := value.(T)
+	if raw, isRaw := value.(json.RawMessage); isRaw && !isT {
+		isT = json.Unmarshal(raw, &typed) == nil
+	}
+	return typed, ok && isT
+}
+
+// WithInputs seeds the mission data with values
+func WithInputs(values map[string]interface{}) Option {
+	return func(m *Mission) {
+		for name, value := range values {
+			m.data.Set(name, value)
+		}
+	}
+}
+
+// WithValue seeds the mission data with a typed value
+func WithValue[T any](k Key[T], value T) Option {
+	return func(m *Mission) {
+		k.Set(m.data, value)
+	}
+}
+
+// Data returns the store shared by the steps of the mission. It lives as long
+// as the mission and keeps its values across runs
+func (m *Mission) Data() *Data {
+	return m.data
+}
@@ -0,0 +1,94 @@
+package mission
+
+import (
+	"sync"
+	"testing"
+)
+
+var (
+	keyOrderID = NewKey[string]("orderID")
+	keyCount   = NewKey[int]("count")
+	keyTotal   = NewKey[float64]("total")
+)
+
+// FuncStep runs fn as its Dispose
+type FuncStep struct {
+	name string
+	fn   func(m *Mission) error
+}
+
+func (s *FuncStep) StepName() string {
+	return s.name
+}
+
+func (s *FuncStep) Dispose(m *Mission, _ []interface{}) error {
+	return s.fn(m)
+}
+
+func TestData_TypedKeys(t *testing.T) {
+	d := NewData()
+
+	if _, ok := keyCount.Get(d); ok {
+		t.Error("Missing key should not be found")
+	}
+	if got := keyCount.GetOr(d, 7); got != 7 {
+		t.Errorf("Expected default 7, got %d", got)
+	}
+
+	keyCount.Set(d, 3)
+	if got, ok := keyCount.Get(d); !ok || got != 3 {
+		t.Errorf("Expected 3, got %d (%v)", got, ok)
+	}
+
+	// A key of another type does not see the value
+	if _, ok := NewKey[string]("count").Get(d); ok {
+		t.Error("Value of another type should not be returned")
+	}
+}
+
+func TestData_Concurrent(t *testing.T) {
+	d := NewData()
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			keyCount.Set(d, i)
+			keyCount.Get(d)
+			d.Snapshot()
+		}(i)
+	}
+	wg.Wait()
+
+	if _, ok := keyCount.Get(d); !ok {
+		t.Error("Expected a value after concurrent writes")
+	}
+}
+
+func TestMission_DataInputsAndOutputs(t *testing.T) {
+	price := &FuncStep{name: "price", fn: func(m *Mission) error {
+		count := keyCount.GetOr(m.Data(), 0)
+		keyTotal.Set(m.Data(), float64(count)*2.5)
+		m.GoNext()
+		return nil
+	}}
+	confirm := &FuncStep{name: "confirm", fn: func(m *Mission) error {
+		id, _ := keyOrderID.Get(m.Data())
+		m.Data().Set("confirmation", "confirmed-"+id)
+		m.GoNext()
+		return nil
+	}}
+
+	m := mustNew(t, []StepDisposer{price, confirm},
+		WithValue(keyCount, 4),
+		WithInputs(map[string]interface{}{"orderID": "A-1"}))
+	m.Start()
+	result := waitResult(t, m)
+
+	if total, ok := keyTotal.Output(result); !ok || total != 10 {
+		t.Errorf("Expected total 10, got %v (%v)", total, ok)
+	}
+	if got := result.Outputs["confirmation"]; got != "confirmed-A-1" {
+		t.Errorf("Expected confirmation output, got %v", got)
+	}
+}
@@ -0,0 +1,62 @@
+package mission
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by the navigation methods of Mission
+var (
+	// ErrNotRunning is returned when the mission is not started or already
+	// finished
+	ErrNotRunning = errors.New("mission is not running")
+	// ErrUnknownStep is returned for navigation to a step the mission lacks
+	ErrUnknownStep = errors.New("unknown step")
+	// ErrJumpPending is returned by Jump when another jump is queued and the
+	// JumpReject conflict policy is in effect
+	ErrJumpPending = errors.New("another jump is pending")
+	// ErrNoHistory is returned by Back without a previous step and by Retry
+	// before the first step
+	ErrNoHistory = errors.New("no step to return to")
+	// ErrNavigationUnsupported is returned by DAG missions, which schedule
+	// their steps from the declared dependencies
+	ErrNavigationUnsupported = errors.New("navigation is not supported by DAG missions")
+)
+
+// ErrAlreadyRunning is returned by Start while a run of the mission is in
+// progress
+var ErrAlreadyRunning = errors.New("mission is already running")
+
+// ErrNoSteps is returned by New and Start for a mission without steps
+var ErrNoSteps = errors.New("mission has no steps")
+
+// ErrStepTimeout is wrapped by the error of a step that exceeded its timeout
+var ErrStepTimeout = errors.New("step timed out")
+
+// ErrAttemptAbandoned is returned by the navigation methods when called by a
+// step attempt the mission stopped waiting for after a timeout
+var ErrAttemptAbandoned = errors.New("step attempt was abandoned")
+
+// StepError records a failed run of a step
+type StepError struct {
+	Step     string
+	Attempts int
+	Err      error
+}
+
+func (e *StepError) Error() string {
+	return fmt.Sprintf("step %s failed after %d attempt(s): %v", e.Step, e.Attempts, e.Err)
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// PanicError is the error of a step whose Dispose panicked
+type PanicError struct {
+	Value interface{}
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("step panicked: %v", e.Value)
+}
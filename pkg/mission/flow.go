@@ -0,0 +1,183 @@
+package mission
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Condition is evaluated against the mission data to pick the flow
+type Condition func(d *Data) bool
+
+// branch is a conditional edge taken instead of the next step
+type branch struct {
+	from string
+	when Condition
+	to   string
+}
+
+// loop repeats the steps from first to last until a condition holds
+type loop struct {
+	first  string
+	last   string
+	until  Condition
+	max    int
+	passes int // completed passes in the current run
+}
+
+// WithBranch continues with the step to instead of the next one when the
+// step from advances and when holds. Branches of a step are evaluated in
+// declaration order, after the loops ending at that step
+func WithBranch(from string, when Condition, to string) Option {
+	return func(m *Mission) {
+		m.branches = append(m.branches, &branch{from: from, when: when, to: to})
+	}
+}
+
+// WithLoop runs the steps from first to last again when last advances, until
+// until holds or max passes have been made
+func WithLoop(first, last string, until Condition, max int) Option {
+	return func(m *Mission) {
+		m.loops = append(m.loops, &loop{first: first, last: last, until: until, max: max})
+	}
+}
+
+// WithAutoAdvance moves on to the next step whenever a step succeeds without
+// requesting any navigation itself
+func WithAutoAdvance() Option {
+	return func(m *Mission) {
+		m.autoAdvance = true
+	}
+}
+
+// validateFlow checks that branches and loops refer to existing steps
+func (m *Mission) validateFlow() error {
+	if m.graph != nil && (len(m.branches) > 0 || len(m.loops) > 0) {
+		return errors.New("branches and loops are not supported by DAG missions")
+	}
+
+	for _, b := range m.branches {
+		if m.indexOf(b.from) < 0 || m.indexOf(b.to) < 0 {
+			return fmt.Errorf("branch %s -> %s refers to an unknown step", b.from, b.to)
+		}
+		if b.when == nil {
+			return fmt.Errorf("branch %s -> %s has no condition", b.from, b.to)
+		}
+	}
+
+	for _, l := range m.loops {
+		first, last := m.indexOf(l.first), m.indexOf(l.last)
+		if first < 0 || last < 0 {
+			return fmt.Errorf("loop %s..%s refers to an unknown step", l.first, l.last)
+		}
+		if first > last {
+			return fmt.Errorf("loop %s..%s starts after it ends", l.first, l.last)
+		}
+		if l.until == nil || l.max < 1 {
+			return fmt.Errorf("loop %s..%s needs a condition and at least one pass", l.first, l.last)
+		}
+	}
+	return nil
+}
+
+// nextIndex picks the step following the current one: a loop back-edge
+// first, then the first matching branch, then the next step of the list
+// Note: This method assumes the caller holds the lock
+func (m *Mission) nextIndex() int {
+	name := m.stepNow.StepName()
+
+	for _, l := range m.loops {
+		if l.last != name {
+			continue
+		}
+		l.passes++
+		if l.passes < l.max && !l.until(m.data) {
+			return m.indexOf(l.first)
+		}
+		l.passes = 0
+	}
+
+	for _, b := range m.branches {
+		if b.from == name && b.when(m.data) {
+			return m.indexOf(b.to)
+		}
+	}
+	return m.indexOf(name) + 1
+}
+
+// resetFlow clears the loop counters for a new run
+// Note: This method assumes the caller holds the lock
+func (m *Mission) resetFlow() {
+	for _, l := range m.loops {
+		l.passes = 0
+	}
+}
+
+// forEachStep fans a body out over the items of a collection
+type forEachStep[T any] struct {
+	name     string
+	items    func(d *Data) []T
+	parallel int
+	body     func(ctx context.Context, m *Mission, item T) error
+}
+
+// ForEach returns a step that runs body once per item returned by items, at
+// most parallel at a time (zero means all at once). The first failing item
+// cancels the remaining ones and fails the step
+func ForEach[T any](name string, items func(d *Data) []T, parallel int, body func(ctx context.Context, m *Mission, item T) error) StepDisposer {
+	return &forEachStep[T]{name: name, items: items, parallel: parallel, body: body}
+}
+
+func (s *forEachStep[T]) StepName() string {
+	return s.name
+}
+
+func (s *forEachStep[T]) Dispose(m *Mission, tags []interface{}) error {
+	return s.DisposeContext(context.Background(), m, tags)
+}
+
+func (s *forEachStep[T]) DisposeContext(ctx context.Context, m *Mission, _ []interface{}) error {
+	items := s.items(m.Data())
+	parallel := s.parallel
+	if parallel <= 0 || parallel > len(items) {
+		parallel = len(items)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	slots := make(chan struct{}, parallel)
+	for i, item := range items {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(i int, item T) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if err := s.body(ctx, m, item); err != nil {
+				once.Do(func() {
+					firstErr = fmt.Errorf("item %d: %w", i, err)
+					cancel()
+				})
+			}
+		}(i, item)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
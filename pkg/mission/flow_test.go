@@ -0,0 +1,170 @@
+package mission
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+var keyApproved = NewKey[bool]("approved")
+
+// namedStep records its name into the mission path and may run fn
+func namedStep(name string, fn func(m *Mission)) StepDisposer {
+	return &FuncStep{name: name, fn: func(m *Mission) error {
+		if fn != nil {
+			fn(m)
+		}
+		return nil
+	}}
+}
+
+func TestMission_Branch(t *testing.T) {
+	tests := []struct {
+		name     string
+		approved bool
+		expected string
+	}{
+		{name: "Approved", approved: true, expected: "check,ship"},
+		{name: "Rejected", approved: false, expected: "check,notify,ship"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps := []StepDisposer{
+				namedStep("check", nil),
+				namedStep("notify", nil),
+				namedStep("ship", nil),
+			}
+			m := mustNew(t, steps,
+				WithAutoAdvance(),
+				WithValue(keyApproved, tt.approved),
+				WithBranch("check", func(d *Data) bool { return keyApproved.GetOr(d, false) }, "ship"))
+			m.Start()
+			result := waitResult(t, m)
+
+			if got := strings.Join(result.StepNames(), ","); got != tt.expected {
+				t.Errorf("Expected path %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestMission_Loop(t *testing.T) {
+	tests := []struct {
+		name      string
+		doneAfter int
+		max       int
+		expected  int
+	}{
+		{name: "Until condition", doneAfter: 2, max: 5, expected: 2},
+		{name: "Max passes", doneAfter: 10, max: 3, expected: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps := []StepDisposer{
+				namedStep("fetch", func(m *Mission) {
+					keyCount.Set(m.Data(), keyCount.GetOr(m.Data(), 0)+1)
+				}),
+				namedStep("check", nil),
+				namedStep("done", nil),
+			}
+			m := mustNew(t, steps,
+				WithAutoAdvance(),
+				WithLoop("fetch", "check", func(d *Data) bool {
+					return keyCount.GetOr(d, 0) >= tt.doneAfter
+				}, tt.max))
+			m.Start()
+			result := waitResult(t, m)
+
+			if got := keyCount.GetOr(m.Data(), 0); got != tt.expected {
+				t.Errorf("Expected %d passes, got %d", tt.expected, got)
+			}
+			names := result.StepNames()
+			if names[len(names)-1] != "done" {
+				t.Errorf("Expected loop to exit to done, got %v", names)
+			}
+		})
+	}
+}
+
+func TestMission_ForEach(t *testing.T) {
+	keyFiles := NewKey[[]string]("files")
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	var running, peak int32
+
+	upload := ForEach("upload", func(d *Data) []string { return keyFiles.GetOr(d, nil) }, 2,
+		func(ctx context.Context, m *Mission, file string) error {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			mu.Lock()
+			seen[file] = true
+			mu.Unlock()
+			return nil
+		})
+
+	m := mustNew(t, []StepDisposer{upload}, WithAutoAdvance(),
+		WithValue(keyFiles, []string{"a", "b", "c", "d", "e"}))
+	m.Start()
+	result := waitResult(t, m)
+
+	if result.Outcome != OutcomeSucceeded || len(seen) != 5 {
+		t.Errorf("Expected every item to be processed, got %v (%s)", seen, result.Outcome)
+	}
+	if atomic.LoadInt32(&peak) > 2 {
+		t.Errorf("Expected at most 2 concurrent items, got %d", peak)
+	}
+}
+
+func TestMission_ForEachFailure(t *testing.T) {
+	upload := ForEach("upload", func(d *Data) []int { return []int{1, 2, 3} }, 1,
+		func(ctx context.Context, m *Mission, item int) error {
+			if item == 2 {
+				return errStep
+			}
+			return nil
+		})
+
+	m := mustNew(t, []StepDisposer{upload})
+	m.Start()
+	result := waitResult(t, m)
+
+	if !errors.Is(result.Err, errStep) || !strings.Contains(result.Err.Error(), "item 1") {
+		t.Errorf("Expected failing item error, got %v", result.Err)
+	}
+}
+
+func TestNew_FlowValidation(t *testing.T) {
+	steps := []StepDisposer{NewMockStep("a"), NewMockStep("b")}
+	always := func(*Data) bool { return true }
+
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "Unknown branch target", opts: []Option{WithBranch("a", always, "x")}},
+		{name: "Branch without condition", opts: []Option{WithBranch("a", nil, "b")}},
+		{name: "Unknown loop step", opts: []Option{WithLoop("x", "b", always, 2)}},
+		{name: "Backwards loop", opts: []Option{WithLoop("b", "a", always, 2)}},
+		{name: "Loop without passes", opts: []Option{WithLoop("a", "b", always, 0)}},
+		{name: "Branch in DAG", opts: []Option{WithDependencies("b", "a"), WithBranch("a", always, "b")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(steps, tt.opts...); err == nil {
+				t.Error("Expected validation error")
+			}
+		})
+	}
+}
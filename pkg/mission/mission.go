@@ -1,9 +1,13 @@
 package mission
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
+
+	"lbox/pkg/clock"
 )
 
 type StepDisposer interface {
@@ -11,130 +15,564 @@ type StepDisposer interface {
 	Dispose(mission *Mission, tags []interface{}) error
 }
 
+// ContextStepDisposer is implemented by steps that want to observe the
+// cancellation of the mission. DisposeContext is called instead of Dispose
+type ContextStepDisposer interface {
+	StepDisposer
+	DisposeContext(ctx context.Context, mission *Mission, tags []interface{}) error
+}
+
+// commandKind tells the executor how to pick the next step
+type commandKind int
+
+const (
+	cmdStart commandKind = iota
+	cmdNext
+	cmdJump
+	cmdBack
+	cmdRetry
+	cmdRestart
+)
+
+// command is a navigation request queued for the executor
+type command struct {
+	kind   commandKind
+	target string
+}
+
 type Mission struct {
-	stepList    []StepDisposer
-	stepNow     StepDisposer
-	running     bool
-	stepRunning bool
-	tags        []interface{}
-	taskList    []string
-	mu          sync.Mutex
+	*missionState
+	// scope is set on the handle a timed step attempt receives, so requests
+	// of an abandoned attempt can be told apart
+	scope *attemptScope
+}
+
+// missionState is shared by a mission and the handles of its step attempts
+type missionState struct {
+	stepList []StepDisposer
+	stepNow  StepDisposer
+	running  bool
+	paused   bool
+	tags     []interface{}
+	data     *Data
+	taskList []command
+	history  []string // steps entered, the current one last
+	wake     chan struct{}
+	ctx      context.Context
+	cancel   context.CancelFunc
+	active   bool          // an executor goroutine is alive
+	done     chan struct{} // closed when the current run completes
+
+	defaultPolicy   ErrorPolicy
+	stepPolicies    map[string]ErrorPolicy
+	onComplete      []func(Result)
+	graph           *graph // set for DAG missions
+	maxParallel     int
+	continueOnError bool
+	branches        []*branch
+	loops           []*loop
+	autoAdvance     bool
+	queued          uint64 // navigation commands requested so far
+	rollback        bool
+	observers       []Observer
+	observerPanic   func(Event, interface{})
+	jumpConflict    JumpConflict
+
+	stepTimeouts       map[string]time.Duration
+	defaultStepTimeout time.Duration
+	timeout            time.Duration // deadline of each run
+	deadlineGrace      time.Duration // extension for a JumpTo handler
+	deadline           time.Time     // deadline of the current run
+	graced             bool          // the current run used its grace period
+	clock              clock.TimerClock
+
+	id             string
+	store          Store
+	restored       *Checkpoint     // applied by the next start
+	resumed        map[string]bool // steps interrupted by the restart
+	completedSteps map[string]bool // DAG steps done before the restart
+	lastRunning    []string        // steps in flight at the last checkpoint
+	saveMu         sync.Mutex      // orders checkpoint saves
+
+	signals       map[string][]interface{}      // payloads not yet waited for
+	waiters       map[string][]chan interface{} // steps waiting for a signal
+	waitDeadlines map[string]time.Time          // WaitForSignal deadlines by step
+
+	outcome    Outcome
+	err        error
+	path       []StepRecord
+	stepErrors []*StepError
+
+	compensated        []string
+	compensationErrors []*StepError
+
+	mu sync.Mutex
 }
 
 func NewMission(stepList []StepDisposer) *Mission {
-	return &Mission{
-		stepList: stepList,
-		taskList: make([]string, 0),
+	return &Mission{missionState: &missionState{
+		stepList:     stepList,
+		data:         NewData(),
+		taskList:     make([]command, 0),
+		wake:         make(chan struct{}, 1),
+		done:         make(chan struct{}),
+		stepPolicies: make(map[string]ErrorPolicy),
+		clock:        clock.Real{},
+	}}
+}
+
+// Start starts the mission in the background. It returns ErrAlreadyRunning
+// while a run of the mission is in progress and ErrNoSteps without steps
+func (m *Mission) Start() error {
+	return m.StartContext(context.Background())
+}
+
+// StartContext starts the mission like Start. Cancelling ctx stops the
+// mission and is observed by steps implementing ContextStepDisposer
+func (m *Mission) StartContext(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.running || m.active {
+		return ErrAlreadyRunning
+	}
+	if len(m.stepList) == 0 {
+		return ErrNoSteps
+	}
+
+	// A new run gets a fresh completion channel once the last one was closed
+	select {
+	case <-m.done:
+		m.done = make(chan struct{})
+	default:
+	}
+
+	m.running = true
+	m.active = true
+	m.paused = false
+	m.outcome = OutcomeUnfinished
+	m.err = nil
+	m.path = nil
+	m.stepErrors = nil
+	m.compensated = nil
+	m.compensationErrors = nil
+	m.history = nil
+	m.signals = make(map[string][]interface{})
+	m.waiters = make(map[string][]chan interface{})
+	m.waitDeadlines = make(map[string]time.Time)
+	m.resumed = nil
+	m.completedSteps = nil
+	m.resetFlow()
+	m.ctx, m.cancel = context.WithCancel(ctx)
+	m.deadline = time.Time{}
+	m.graced = false
+	if m.timeout > 0 {
+		m.deadline = m.clock.Now().Add(m.timeout)
+	}
+	restored := m.restored
+	if restored != nil {
+		m.restored = nil
+		m.restore(restored)
+	}
+	if m.graph != nil {
+		go m.runDAG(m.ctx)
+		return nil
+	}
+	if restored == nil {
+		m.taskList = append([]command{{kind: cmdStart, target: m.stepList[0].StepName()}}, m.taskList...)
 	}
+	go m.run(m.ctx)
+	m.signal()
+	return nil
 }
 
-func (m *Mission) Start() {
+// Stop aborts the mission. The running step is cancelled through its context
+// and no further steps are executed
+func (m *Mission) Stop() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if m.running {
-		fmt.Println("already started")
+	if !m.running {
 		return
 	}
+	m.finish(OutcomeStopped, context.Canceled)
+}
 
-	m.running = true
-	m.stepNow = m.stepList[0]
-	m.stepRunning = true
-	_ = m.stepNow.Dispose(m, m.tags)
-	m.stepRunning = false
-	go m.tick()
+// Pause holds queued commands until Resume. The running step is not
+// interrupted
+func (m *Mission) Pause() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.paused = true
+}
+
+// Resume continues a paused mission
+func (m *Mission) Resume() {
+	m.mu.Lock()
+	m.paused = false
+	m.mu.Unlock()
+	m.signal()
 }
 
-func (m *Mission) GoNext() {
+// IsRunning returns whether the mission has been started and not yet
+// finished or stopped
+func (m *Mission) IsRunning() bool {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.taskList = append(m.taskList, "next")
+	return m.running
 }
 
-func (m *Mission) tick() {
+// IsPaused returns whether the mission is paused
+func (m *Mission) IsPaused() bool {
 	m.mu.Lock()
-	
-	if !m.running {
+	defer m.mu.Unlock()
+	return m.paused
+}
+
+// GoNext queues a move to the step following the current one. Moving past
+// the last step completes the mission
+func (m *Mission) GoNext() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkNavigation(); err != nil {
+		return err
+	}
+	m.enqueue(command{kind: cmdNext})
+	m.signal()
+	return nil
+}
+
+// Jump queues a move to the named step. When another jump is already queued
+// the JumpConflict policy of the mission decides, by default the new jump is
+// rejected with ErrJumpPending
+func (m *Mission) Jump(stepName string) error {
+	m.mu.Lock()
+	if err := m.checkNavigation(); err != nil {
 		m.mu.Unlock()
-		return
+		return err
 	}
-	
-	if m.stepRunning {
+	if m.indexOf(stepName) < 0 {
 		m.mu.Unlock()
-		panic("no concurrency allowed")
+		return fmt.Errorf("%w: %s", ErrUnknownStep, stepName)
 	}
 
-	if len(m.taskList) == 0 {
+	pending := -1
+	for i, task := range m.taskList {
+		if task.kind == cmdJump {
+			pending = i
+		}
+	}
+	switch {
+	case pending < 0 || m.jumpConflict == JumpQueue:
+		m.enqueue(command{kind: cmdJump, target: stepName})
+	case m.jumpConflict == JumpReplace:
+		m.taskList[pending].target = stepName
+		m.queued++
+	default:
 		m.mu.Unlock()
-		time.Sleep(time.Millisecond)
-		go m.tick()
-		return
+		return fmt.Errorf("%w: %v", ErrJumpPending, m.pendingNames())
+	}
+
+	current := m.currentName()
+	m.mu.Unlock()
+	m.signal()
+	m.notify(Event{Kind: EventJumpRequested, Step: current, Target: stepName})
+	return nil
+}
+
+// checkNavigation returns why navigation requests cannot be queued, if so
+// Note: This method assumes the caller holds the lock
+func (m *Mission) checkNavigation() error {
+	if m.scope != nil && m.scope.abandoned {
+		return ErrAttemptAbandoned
+	}
+	if m.graph != nil {
+		return ErrNavigationUnsupported
+	}
+	if !m.running {
+		return ErrNotRunning
 	}
+	return nil
+}
+
+// currentName returns the name of the current step, empty before the first
+// Note: This method assumes the caller holds the lock
+func (m *Mission) currentName() string {
+	if m.stepNow == nil {
+		return ""
+	}
+	return m.stepNow.StepName()
+}
+
+// enqueue appends a navigation request to the queue
+// Note: This method assumes the caller holds the lock
+func (m *Mission) enqueue(cmd command) {
+	m.taskList = append(m.taskList, cmd)
+	m.queued++
+}
+
+// signal wakes the executor without blocking; one pending wake-up is enough
+// because the executor drains the whole queue each time
+func (m *Mission) signal() {
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
 
-	currentTask := m.taskList[0]
-	m.taskList = m.taskList[1:]
-	m.stepRunning = true
-
-	if currentTask == "next" {
-		next := false
-		for _, iterator := range m.stepList {
-			if next {
-				m.stepNow = iterator
-				_ = m.stepNow.Dispose(m, m.tags)
-				next = false
-				break
-			} else {
-				if iterator.StepName() == m.stepNow.StepName() {
-					next = true
-				}
+// run is the single executor goroutine of a mission. It sleeps until a
+// command is queued and exits once the mission stops running or ctx is done
+func (m *Mission) run(ctx context.Context) {
+	defer m.complete()
+	expired, stop := m.deadlineTimer()
+	defer func() { stop() }()
+	for {
+		select {
+		case <-m.wake:
+			if !m.drain(ctx) {
+				return
 			}
+		case <-expired:
+			if m.expire() {
+				return
+			}
+			// The grace period moved the deadline
+			stop()
+			expired, stop = m.deadlineTimer()
+		case <-ctx.Done():
+			m.mu.Lock()
+			if m.running {
+				m.finish(OutcomeStopped, ctx.Err())
+			}
+			m.mu.Unlock()
+			return
 		}
+	}
+}
 
-		if next {
-			m.running = false
-			m.stepRunning = false
+// complete runs when the executor exits: it rolls back completed steps when
+// enabled, closes Done and then calls the OnComplete callbacks
+func (m *Mission) complete() {
+	m.mu.Lock()
+	rollback := m.rollback && (m.outcome == OutcomeFailed || m.outcome == OutcomeStopped)
+	path := append([]StepRecord(nil), m.path...)
+	ctx := m.ctx
+	m.mu.Unlock()
+
+	if rollback {
+		m.compensate(context.WithoutCancel(ctx), path)
+	}
+
+	m.checkpoint()
+
+	m.mu.Lock()
+	m.active = false
+	result := m.result()
+	done := m.done
+	callbacks := m.onComplete
+	m.mu.Unlock()
+
+	close(done)
+	m.notify(Event{Kind: EventMissionCompleted, Result: result})
+	for _, fn := range callbacks {
+		fn(result)
+	}
+}
+
+// drain applies the queued commands in order and reports whether the mission
+// is still running afterwards. Steps run without the lock held, so Dispose may
+// call GoNext or Jump; those requests are applied after the step returns
+func (m *Mission) drain(ctx context.Context) bool {
+	for {
+		m.mu.Lock()
+		if !m.running || m.paused || len(m.taskList) == 0 || ctx.Err() != nil {
+			running := m.running
 			m.mu.Unlock()
-			return
+			return running
 		}
-	} else {
-		for _, iterator := range m.stepList {
-			if iterator.StepName() == currentTask {
-				m.stepNow = iterator
-				_ = m.stepNow.Dispose(m, m.tags)
-				break
-			}
+		cmd := m.taskList[0]
+		m.taskList = m.taskList[1:]
+		step := m.resolve(cmd)
+		m.mu.Unlock()
+
+		if step != nil && m.checkpoint(step.StepName()) {
+			m.execute(ctx, step)
+			m.checkpoint()
 		}
 	}
+}
 
-	m.stepRunning = false
-	m.mu.Unlock()
-	
-	time.Sleep(time.Millisecond)
-	go m.tick()
+// resolve makes the step selected by cmd the current one and returns it. A
+// "next" command past the last step finishes the mission and returns nil
+// Note: This method assumes the caller holds the lock
+func (m *Mission) resolve(cmd command) StepDisposer {
+	var index int
+	switch cmd.kind {
+	case cmdNext:
+		index = m.nextIndex()
+		if index >= len(m.stepList) {
+			m.finish(OutcomeSucceeded, nil)
+			return nil
+		}
+	case cmdBack:
+		if len(m.history) < 2 {
+			return nil
+		}
+		m.history = m.history[:len(m.history)-1]
+		m.stepNow = m.stepList[m.indexOf(m.history[len(m.history)-1])]
+		return m.stepNow
+	case cmdRetry:
+		return m.stepNow
+	case cmdRestart:
+		m.history = m.history[:0]
+		m.resetFlow()
+		index = 0
+	default:
+		index = m.indexOf(cmd.target)
+	}
+
+	m.stepNow = m.stepList[index]
+	m.history = append(m.history, m.stepNow.StepName())
+	return m.stepNow
 }
 
-func (m *Mission) Jump(stepName string) {
+// execute runs step and applies its error policy when it fails
+func (m *Mission) execute(ctx context.Context, step StepDisposer) {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-	
-	// Check if there's already a non-"next" task in the queue
-	for _, task := range m.taskList {
-		if task != "next" {
-			panic(fmt.Sprintf("error task list: cannot add jump task when another jump task exists: %v", m.taskList))
+	queued := m.queued
+	m.mu.Unlock()
+
+	policy := m.policyFor(step.StepName())
+	record := m.attempt(ctx, step, policy)
+	if record.Err != nil && ctx.Err() == nil {
+		if errors.Is(record.Err, errDeadline) && !m.extendDeadline(policy) {
+			policy = Fail()
 		}
+		m.handleFailure(policy, record)
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.path = append(m.path, record)
+	if m.autoAdvance && record.Err == nil && m.running && m.queued == queued {
+		m.enqueue(command{kind: cmdNext})
 	}
-	
-	// Validate if the step exists
-	stepExists := false
-	for _, iterator := range m.stepList {
-		if iterator.StepName() == stepName {
-			stepExists = true
-			m.taskList = append(m.taskList, stepName)
+}
+
+// attempt runs step until it succeeds, the retries of policy run out or ctx
+// is done, and returns the record of the execution
+func (m *Mission) attempt(ctx context.Context, step StepDisposer, policy ErrorPolicy) StepRecord {
+	m.mu.Lock()
+	info := m.stepInfo(step.StepName())
+	m.mu.Unlock()
+
+	record := StepRecord{Name: step.StepName(), Started: m.clock.Now()}
+	m.notify(Event{Kind: EventStepStarted, At: record.Started, Step: record.Name})
+	sink := &nestedSink{}
+	ctx = context.WithValue(ctx, nestedKey{}, sink)
+	backoffCtx, cancel := m.backoffContext(ctx)
+	defer cancel()
+	for {
+		record.Attempts++
+		info.Attempt = record.Attempts
+		record.Err = m.disposeWithin(context.WithValue(ctx, stepInfoKey{}, info), step)
+		if record.Err == nil || ctx.Err() != nil || errors.Is(record.Err, errDeadline) {
+			break
+		}
+		if policy.Action != ActionRetry || record.Attempts >= policy.MaxAttempts {
+			break
+		}
+		if !policy.wait(backoffCtx, m.clock, record.Attempts) {
+			if ctx.Err() == nil {
+				record.Err = m.timedOut(errDeadline, 0, record.Err)
+			}
 			break
 		}
 	}
-	
-	if !stepExists {
-		fmt.Printf("warning: step %s not found\n", stepName)
+
+	record.Duration = m.clock.Now().Sub(record.Started)
+	record.Nested = sink.get()
+	m.notify(Event{Kind: EventStepFinished, Step: record.Name, Record: record})
+	return record
+}
+
+// handleFailure records a step failure and applies the final action of policy.
+// The navigation of Skip and JumpTo runs before any command already queued
+func (m *Mission) handleFailure(policy ErrorPolicy, record StepRecord) {
+	m.mu.Lock()
+	stepErr := &StepError{Step: record.Name, Attempts: record.Attempts, Err: record.Err}
+	m.path = append(m.path, record)
+	m.stepErrors = append(m.stepErrors, stepErr)
+	switch policy.Action {
+	case ActionSkip:
+		m.taskList = append([]command{{kind: cmdNext}}, m.taskList...)
+	case ActionJump:
+		m.taskList = append([]command{{kind: cmdJump, target: policy.Target}}, m.taskList...)
+		m.mu.Unlock()
+		m.notify(Event{Kind: EventJumpRequested, Step: record.Name, Target: policy.Target})
+		return
+	default:
+		m.finish(OutcomeFailed, stepErr)
+	}
+	m.mu.Unlock()
+}
+
+// policyFor returns the error policy of the named step
+func (m *Mission) policyFor(stepName string) ErrorPolicy {
+	if policy, ok := m.stepPolicies[stepName]; ok {
+		return policy
+	}
+	return m.defaultPolicy
+}
+
+// finish ends the run with outcome and err and releases its context. Done is
+// closed once the executor has returned from the running step
+// Note: This method assumes the caller holds the lock
+func (m *Mission) finish(outcome Outcome, err error) {
+	m.running = false
+	m.outcome = outcome
+	m.err = err
+	m.taskList = m.taskList[:0]
+	m.cancel()
+}
+
+// dispose runs step, passing ctx to steps that accept it. A panic in the step
+// is returned as a PanicError
+func (m *Mission) dispose(ctx context.Context, step StepDisposer) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r}
+		}
+	}()
+
+	if cs, ok := step.(ContextStepDisposer); ok {
+		return cs.DisposeContext(ctx, m, m.tags)
+	}
+	return step.Dispose(m, m.tags)
+}
+
+// indexOf returns the position of the named step, or -1
+func (m *Mission) indexOf(stepName string) int {
+	for i, step := range m.stepList {
+		if step.StepName() == stepName {
+			return i
+		}
+	}
+	return -1
+}
+
+// pendingNames describes the queued commands for error messages
+func (m *Mission) pendingNames() []string {
+	names := make([]string, 0, len(m.taskList))
+	for _, task := range m.taskList {
+		switch task.kind {
+		case cmdStart, cmdJump:
+			names = append(names, task.target)
+		default:
+			names = append(names, task.kind.String())
+		}
 	}
+	return names
 }
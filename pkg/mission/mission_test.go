@@ -1,7 +1,11 @@
 package mission
 
 import (
+	"context"
+	"errors"
+	"runtime"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -102,23 +106,30 @@ func TestMission_Jump(t *testing.T) {
 
 func TestMission_ConcurrencyControl(t *testing.T) {
 	step1 := NewMockStep("step1")
-	executionCount := 0
+	var executionCount int32
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
 	
 	step1.onDispose = func() {
-		time.Sleep(10 * time.Millisecond) // Reduced wait time
-		executionCount++
+		atomic.AddInt32(&executionCount, 1)
+		started <- struct{}{}
+		<-release
 	}
 	
 	mission := NewMission([]StepDisposer{step1})
 	
 	mission.Start()
-	time.Sleep(5 * time.Millisecond) // Wait for first execution to start
-	mission.Start() // Try to start again
+	<-started // Wait for first execution to start
+	if err := mission.Start(); !errors.Is(err, ErrAlreadyRunning) {
+		t.Errorf("Expected ErrAlreadyRunning for a second start, got %v", err)
+	}
 	
-	time.Sleep(50 * time.Millisecond) // Ensure enough time for completion
+	close(release)
+	mission.GoNext()
+	waitResult(t, mission)
 	
-	if executionCount != 1 {
-		t.Errorf("Step1 should be executed only once, but was executed %d times", executionCount)
+	if count := atomic.LoadInt32(&executionCount); count != 1 {
+		t.Errorf("Step1 should be executed only once, but was executed %d times", count)
 	}
 }
 
@@ -126,34 +137,82 @@ func TestMission_TaskQueueValidation(t *testing.T) {
 	step1 := NewMockStep("step1")
 	step2 := NewMockStep("step2")
 	
+	if err := NewMission(nil).Start(); !errors.Is(err, ErrNoSteps) {
+		t.Errorf("Expected ErrNoSteps for a mission without steps, got %v", err)
+	}
+	
 	mission := NewMission([]StepDisposer{step1, step2})
 	
-	// Use channel to ensure we catch the panic
-	done := make(chan bool)
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				done <- true
-				return
+	if err := mission.GoNext(); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("Expected ErrNotRunning before start, got %v", err)
+	}
+	
+	mission.Start()
+	mission.Pause() // Hold the queue so both jumps are pending
+	
+	if err := mission.Jump("step3"); !errors.Is(err, ErrUnknownStep) {
+		t.Errorf("Expected ErrUnknownStep, got %v", err)
+	}
+	if err := mission.Jump("step1"); err != nil {
+		t.Fatalf("First jump failed: %v", err)
+	}
+	if err := mission.Jump("step2"); !errors.Is(err, ErrJumpPending) {
+		t.Errorf("Expected ErrJumpPending for a second jump, got %v", err)
+	}
+	mission.Stop()
+}
+
+func TestMission_JumpConflict(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   JumpConflict
+		expected []string
+	}{
+		{"replace", JumpReplace, []string{"step1", "step3"}},
+		{"queue", JumpQueue, []string{"step1", "step2", "step3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var order []string
+			steps := make([]StepDisposer, 0, 3)
+			for _, name := range []string{"step1", "step2", "step3"} {
+				step := NewMockStep(name)
+				stepName := name
+				step.onDispose = func() {
+					mu.Lock()
+					defer mu.Unlock()
+					order = append(order, stepName)
+				}
+				steps = append(steps, step)
 			}
-			done <- false
-		}()
-		
-		mission.Start()
-		time.Sleep(20 * time.Millisecond) // Increased wait time
-		
-		mission.Jump("step1")
-		mission.Jump("step2") // This should trigger panic immediately
-	}()
-	
-	// Wait for goroutine completion
-	select {
-	case didPanic := <-done:
-		if !didPanic {
-			t.Error("Should panic when executing multiple jumps")
-		}
-	case <-time.After(100 * time.Millisecond):
-		t.Error("Test timeout")
+			mission, err := New(steps, WithJumpConflict(tt.policy))
+			if err != nil {
+				t.Fatalf("New failed: %v", err)
+			}
+
+			mission.Start()
+			mission.Pause()
+			for _, target := range []string{"step2", "step3"} {
+				if err := mission.Jump(target); err != nil {
+					t.Fatalf("Jump %s failed: %v", target, err)
+				}
+			}
+			mission.Resume()
+			time.Sleep(10 * time.Millisecond)
+			mission.Stop()
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(order) != len(tt.expected) {
+				t.Fatalf("Expected order %v, got %v", tt.expected, order)
+			}
+			for i := range tt.expected {
+				if order[i] != tt.expected[i] {
+					t.Fatalf("Expected order %v, got %v", tt.expected, order)
+				}
+			}
+		})
 	}
 }
 
@@ -171,7 +230,189 @@ func TestMission_CompletionState(t *testing.T) {
 	mission.GoNext() // Try to move to non-existent next step
 	time.Sleep(10 * time.Millisecond)
 	
-	if mission.running {
+	if mission.IsRunning() {
 		t.Error("Mission should stop running after completing all steps")
 	}
-} 
\ No newline at end of file
+}
+
+func TestMission_CommandOrdering(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	steps := make([]StepDisposer, 0, 3)
+	for _, name := range []string{"step1", "step2", "step3"} {
+		step := NewMockStep(name)
+		stepName := name
+		step.onDispose = func() {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, stepName)
+		}
+		steps = append(steps, step)
+	}
+
+	mission := NewMission(steps)
+	mission.Start()
+	mission.GoNext()
+	mission.Jump("step1")
+	mission.GoNext()
+	mission.GoNext()
+	time.Sleep(10 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	expected := []string{"step1", "step2", "step1", "step2", "step3"}
+	if len(order) != len(expected) {
+		t.Fatalf("Expected order %v, got %v", expected, order)
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Fatalf("Expected order %v, got %v", expected, order)
+		}
+	}
+}
+
+func TestMission_ExecutorExitsWhenDone(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	mission := NewMission([]StepDisposer{NewMockStep("step1")})
+	mission.Start()
+	mission.GoNext()
+	time.Sleep(10 * time.Millisecond)
+
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("Expected executor goroutine to exit, goroutines went from %d to %d", before, after)
+	}
+}
+
+// chainStep advances the mission from inside Dispose
+type chainStep struct {
+	*MockStep
+	action func(m *Mission)
+}
+
+func (s *chainStep) Dispose(m *Mission, tags []interface{}) error {
+	_ = s.MockStep.Dispose(m, tags)
+	s.action(m)
+	return nil
+}
+
+func TestMission_NavigateFromDispose(t *testing.T) {
+	step1 := &chainStep{MockStep: NewMockStep("step1"), action: func(m *Mission) { m.GoNext() }}
+	step2 := &chainStep{MockStep: NewMockStep("step2"), action: func(m *Mission) { m.Jump("step4") }}
+	step3 := NewMockStep("step3")
+	step4 := &chainStep{MockStep: NewMockStep("step4"), action: func(m *Mission) { m.GoNext() }}
+
+	mission := NewMission([]StepDisposer{step1, step2, step3, step4})
+	mission.Start()
+	time.Sleep(10 * time.Millisecond)
+
+	if !step1.WasExecuted() || !step2.WasExecuted() || !step4.WasExecuted() {
+		t.Error("Steps navigating from Dispose should not deadlock")
+	}
+	if step3.WasExecuted() {
+		t.Error("step3 should be skipped by the jump")
+	}
+
+	mission.mu.Lock()
+	defer mission.mu.Unlock()
+	if mission.running {
+		t.Error("Mission should finish after GoNext from the last step")
+	}
+}
+
+// ContextStep blocks until its context is cancelled
+type ContextStep struct {
+	*MockStep
+	started   chan struct{}
+	cancelled chan struct{}
+}
+
+func NewContextStep(name string) *ContextStep {
+	return &ContextStep{
+		MockStep:  NewMockStep(name),
+		started:   make(chan struct{}),
+		cancelled: make(chan struct{}),
+	}
+}
+
+func (s *ContextStep) DisposeContext(ctx context.Context, m *Mission, tags []interface{}) error {
+	_ = s.MockStep.Dispose(m, tags)
+	close(s.started)
+	<-ctx.Done()
+	close(s.cancelled)
+	return ctx.Err()
+}
+
+func TestMission_Stop(t *testing.T) {
+	step1 := NewContextStep("step1")
+	step2 := NewMockStep("step2")
+
+	mission := NewMission([]StepDisposer{step1, step2})
+	mission.Start()
+	<-step1.started
+
+	mission.GoNext()
+	mission.Stop()
+
+	select {
+	case <-step1.cancelled:
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("Stop should cancel the running step's context")
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	if mission.IsRunning() {
+		t.Error("Mission should not be running after Stop")
+	}
+	if step2.WasExecuted() {
+		t.Error("No steps should run after Stop")
+	}
+}
+
+func TestMission_StartContextCancel(t *testing.T) {
+	step1 := NewContextStep("step1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mission := NewMission([]StepDisposer{step1})
+	mission.StartContext(ctx)
+	<-step1.started
+
+	cancel()
+	select {
+	case <-step1.cancelled:
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("Cancelling the start context should reach the step")
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	if mission.IsRunning() {
+		t.Error("Mission should stop when its context is cancelled")
+	}
+}
+
+func TestMission_PauseResume(t *testing.T) {
+	step1 := NewMockStep("step1")
+	step2 := NewMockStep("step2")
+
+	mission := NewMission([]StepDisposer{step1, step2})
+	mission.Start()
+	time.Sleep(10 * time.Millisecond)
+
+	mission.Pause()
+	mission.GoNext()
+	time.Sleep(10 * time.Millisecond)
+
+	if !mission.IsPaused() {
+		t.Error("Mission should report paused")
+	}
+	if step2.WasExecuted() {
+		t.Error("Paused mission should not execute queued steps")
+	}
+
+	mission.Resume()
+	time.Sleep(10 * time.Millisecond)
+
+	if !step2.WasExecuted() {
+		t.Error("Resumed mission should execute queued steps")
+	}
+}
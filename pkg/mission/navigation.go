@@ -0,0 +1,74 @@
+package mission
+
+// Back queues a return to the step executed before the current one. It follows
+// the steps actually entered, including jumps, and runs that step again
+func (m *Mission) Back() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkNavigation(); err != nil {
+		return err
+	}
+	if m.projectedDepth() < 2 {
+		return ErrNoHistory
+	}
+	m.enqueue(command{kind: cmdBack})
+	m.signal()
+	return nil
+}
+
+// Retry queues another execution of the current step
+func (m *Mission) Retry() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkNavigation(); err != nil {
+		return err
+	}
+	if m.projectedDepth() < 1 {
+		return ErrNoHistory
+	}
+	m.enqueue(command{kind: cmdRetry})
+	m.signal()
+	return nil
+}
+
+// Restart queues a move to the first step, clearing the history and the loop
+// counters. Mission data is kept
+func (m *Mission) Restart() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkNavigation(); err != nil {
+		return err
+	}
+	m.enqueue(command{kind: cmdRestart})
+	m.signal()
+	return nil
+}
+
+// History returns the steps entered so far, the current one last. Back pops
+// the current step from it
+func (m *Mission) History() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.history...)
+}
+
+// projectedDepth returns the length the history will have once the queued
+// commands are applied
+// Note: This method assumes the caller holds the lock
+func (m *Mission) projectedDepth() int {
+	depth := len(m.history)
+	for _, task := range m.taskList {
+		switch task.kind {
+		case cmdStart, cmdNext, cmdJump:
+			depth++
+		case cmdBack:
+			depth--
+		case cmdRestart:
+			depth = 1
+		}
+	}
+	return depth
+}
@@ -0,0 +1,90 @@
+package mission
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// waitPath waits until the mission executed n steps and returns their names
+func waitPath(t *testing.T, m *Mission, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		names := m.Result().StepNames()
+		if len(names) >= n {
+			return names
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d executed steps, got %v", n, names)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestMission_BackRetryRestart(t *testing.T) {
+	steps := []StepDisposer{namedStep("a", nil), namedStep("b", nil), namedStep("c", nil), namedStep("d", nil)}
+	m := mustNew(t, steps)
+
+	if err := m.Retry(); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("Expected ErrNotRunning before start, got %v", err)
+	}
+
+	m.Start()
+	m.Pause()
+	if err := m.Back(); !errors.Is(err, ErrNoHistory) {
+		t.Errorf("Expected ErrNoHistory on the first step, got %v", err)
+	}
+	for _, nav := range []func() error{
+		m.GoNext,
+		func() error { return m.Jump("d") },
+		m.Back,
+		m.Back,
+		m.Retry,
+		m.Restart,
+		m.GoNext,
+	} {
+		if err := nav(); err != nil {
+			t.Fatalf("Navigation failed: %v", err)
+		}
+	}
+	if err := m.Back(); err != nil {
+		t.Fatalf("Back after Restart and GoNext failed: %v", err)
+	}
+	m.Resume()
+
+	expected := []string{"a", "b", "d", "b", "a", "a", "a", "b", "a"}
+	if got := waitPath(t, m, len(expected)); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected path %v, got %v", expected, got)
+	}
+	if history := m.History(); !reflect.DeepEqual(history, []string{"a"}) {
+		t.Errorf("Expected history [a], got %v", history)
+	}
+	m.Stop()
+}
+
+func TestMission_BackAfterLoop(t *testing.T) {
+	steps := []StepDisposer{namedStep("intro", nil), namedStep("form", nil), namedStep("review", nil)}
+	m := mustNew(t, steps, WithLoop("form", "form", func(*Data) bool { return false }, 2))
+
+	m.Start()
+	m.Pause()
+	for i := 0; i < 3; i++ {
+		if err := m.GoNext(); err != nil {
+			t.Fatalf("GoNext failed: %v", err)
+		}
+	}
+	if err := m.Back(); err != nil {
+		t.Fatalf("Back failed: %v", err)
+	}
+	m.Resume()
+
+	// Back returns to the second pass of the loop, not to the previous step
+	// of the list
+	expected := []string{"intro", "form", "form", "review", "form"}
+	if got := waitPath(t, m, len(expected)); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected path %v, got %v", expected, got)
+	}
+	m.Stop()
+}
@@ -0,0 +1,118 @@
+package mission
+
+import (
+	"time"
+)
+
+// EventKind identifies a lifecycle event of a mission
+type EventKind int
+
+const (
+	// EventStepStarted is reported before a step executes, once per execution
+	EventStepStarted EventKind = iota
+	// EventStepFinished is reported after a step and its retries returned
+	EventStepFinished
+	// EventJumpRequested is reported when a jump is queued by Jump or by a
+	// JumpTo error policy
+	EventJumpRequested
+	// EventMissionCompleted is reported once per run after Done is closed
+	EventMissionCompleted
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventStepStarted:
+		return "step started"
+	case EventStepFinished:
+		return "step finished"
+	case EventJumpRequested:
+		return "jump requested"
+	case EventMissionCompleted:
+		return "mission completed"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes a lifecycle event reported to observers
+type Event struct {
+	Kind EventKind
+	At   time.Time
+	// Step is the step the event refers to. For jumps it is the current step
+	Step string
+	// Record holds the execution of a finished step, with its duration and error
+	Record StepRecord
+	// Target is the destination of a requested jump
+	Target string
+	// Result is the result of a completed mission
+	Result Result
+	// Path names the SubMission steps the event was forwarded through,
+	// outermost first. It is empty for events of the observed mission itself
+	Path []string
+}
+
+// Observer receives the lifecycle events of a mission. Observe runs on the
+// executor without the mission lock held, concurrently for parallel steps of
+// a DAG mission. A panicking observer is recovered and does not affect the
+// mission or the other observers, see WithObserverPanicHandler
+type Observer interface {
+	Observe(m *Mission, e Event)
+}
+
+// ObserverFunc adapts a function to the Observer interface
+type ObserverFunc func(m *Mission, e Event)
+
+func (f ObserverFunc) Observe(m *Mission, e Event) {
+	f(m, e)
+}
+
+// WithObserver registers an observer, see AddObserver
+func WithObserver(o Observer) Option {
+	return func(m *Mission) {
+		m.observers = append(m.observers, o)
+	}
+}
+
+// WithObserverPanicHandler registers fn to be called with the event and the
+// recovered value when an observer panics. Without a handler the panic is
+// dropped
+func WithObserverPanicHandler(fn func(e Event, recovered interface{})) Option {
+	return func(m *Mission) {
+		m.observerPanic = fn
+	}
+}
+
+// AddObserver registers o to receive the events of the mission
+func (m *Mission) AddObserver(o Observer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.observers = append(m.observers, o)
+}
+
+// notify reports e to every observer
+// Note: This method must be called without the lock held
+func (m *Mission) notify(e Event) {
+	m.mu.Lock()
+	observers := m.observers
+	onPanic := m.observerPanic
+	m.mu.Unlock()
+
+	if e.At.IsZero() {
+		e.At = m.clock.Now()
+	}
+	for _, o := range observers {
+		if r := observe(m, o, e); r != nil && onPanic != nil {
+			onPanic(e, r)
+		}
+	}
+}
+
+// observe calls o, isolating the mission from its panics, and returns the
+// recovered value
+func observe(m *Mission, o Observer, e Event) (recovered interface{}) {
+	defer func() {
+		recovered = recover()
+	}()
+	o.Observe(m, e)
+	return nil
+}
@@ -0,0 +1,122 @@
+package mission
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// eventLog records the observed events of a mission
+type eventLog struct {
+	events []Event
+	mu     sync.Mutex
+}
+
+func (l *eventLog) Observe(_ *Mission, e Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, e)
+}
+
+func (l *eventLog) Lines() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lines := make([]string, 0, len(l.events))
+	for _, e := range l.events {
+		switch e.Kind {
+		case EventJumpRequested:
+			lines = append(lines, fmt.Sprintf("%s:%s->%s", e.Kind, e.Step, e.Target))
+		case EventMissionCompleted:
+			lines = append(lines, fmt.Sprintf("%s:%s", e.Kind, e.Result.Outcome))
+		default:
+			lines = append(lines, fmt.Sprintf("%s:%s", e.Kind, e.Step))
+		}
+	}
+	return lines
+}
+
+// observeRun starts m and waits until its completion event was observed
+func observeRun(t *testing.T, m *Mission) Result {
+	t.Helper()
+	// Observers are notified before the OnComplete callbacks of the same run
+	observed := make(chan struct{})
+	m.OnComplete(func(Result) { close(observed) })
+	m.Start()
+	result := waitResult(t, m)
+	<-observed
+	return result
+}
+
+func TestMission_Observer(t *testing.T) {
+	steps := []StepDisposer{
+		namedStep("prepare", func(m *Mission) { m.GoNext() }),
+		&FlakyStep{name: "charge", failures: 1},
+		namedStep("recover", func(m *Mission) { m.GoNext() }),
+	}
+
+	log := &eventLog{}
+	var mu sync.Mutex
+	var panics []string
+	m := mustNew(t, steps,
+		WithStepPolicy("charge", JumpTo("recover")),
+		WithObserver(log),
+		WithObserver(ObserverFunc(func(*Mission, Event) { panic("broken observer") })),
+		WithObserverPanicHandler(func(e Event, recovered interface{}) {
+			mu.Lock()
+			defer mu.Unlock()
+			panics = append(panics, fmt.Sprintf("%s:%v", e.Kind, recovered))
+		}),
+	)
+	result := observeRun(t, m)
+
+	if result.Outcome != OutcomeSucceeded {
+		t.Fatalf("Panicking observer should not fail the mission, got %s", result.Outcome)
+	}
+	mu.Lock()
+	if len(panics) != 8 || panics[0] != "step started:broken observer" {
+		t.Errorf("Expected every panic to reach the handler, got %v", panics)
+	}
+	mu.Unlock()
+	expected := []string{
+		"step started:prepare",
+		"step finished:prepare",
+		"step started:charge",
+		"step finished:charge",
+		"jump requested:charge->recover",
+		"step started:recover",
+		"step finished:recover",
+		"mission completed:succeeded",
+	}
+	lines := log.Lines()
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected events %v, got %v", expected, lines)
+	}
+	for i, line := range expected {
+		if lines[i] != line {
+			t.Errorf("Event %d: expected %s, got %s", i, line, lines[i])
+		}
+	}
+}
+
+func TestMission_ObserverFailure(t *testing.T) {
+	log := &eventLog{}
+	m := mustNew(t, []StepDisposer{&FlakyStep{name: "only", failures: 1}})
+	m.AddObserver(log)
+	observeRun(t, m)
+
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	if len(log.events) != 3 {
+		t.Fatalf("Expected 3 events, got %d", len(log.events))
+	}
+	finished := log.events[1]
+	if finished.Kind != EventStepFinished || finished.Record.Err == nil || finished.Record.Duration < 0 {
+		t.Errorf("Expected finished event with the step error, got %+v", finished)
+	}
+	completed := log.events[2]
+	var stepErr *StepError
+	if completed.Kind != EventMissionCompleted || completed.Result.Outcome != OutcomeFailed || !errors.As(completed.Result.Err, &stepErr) {
+		t.Errorf("Expected failed completion event, got %+v", completed)
+	}
+}
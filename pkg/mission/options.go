@@ -0,0 +1,108 @@
+package mission
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Option configures a Mission created with New
+type Option func(m *Mission)
+
+// WithErrorPolicy sets the policy for failing steps without their own policy.
+// The default is Fail
+func WithErrorPolicy(policy ErrorPolicy) Option {
+	return func(m *Mission) {
+		m.defaultPolicy = policy
+	}
+}
+
+// WithStepPolicy sets the policy applied when the named step fails
+func WithStepPolicy(stepName string, policy ErrorPolicy) Option {
+	return func(m *Mission) {
+		m.stepPolicies[stepName] = policy
+	}
+}
+
+// JumpConflict selects how Jump handles a jump queued while another one is
+// still pending
+type JumpConflict int
+
+const (
+	// JumpReject refuses the new jump with ErrJumpPending
+	JumpReject JumpConflict = iota
+	// JumpReplace retargets the last pending jump to the new step
+	JumpReplace
+	// JumpQueue queues every jump, they run in order
+	JumpQueue
+)
+
+// WithJumpConflict sets the policy for conflicting jumps, the default is
+// JumpReject
+func WithJumpConflict(policy JumpConflict) Option {
+	return func(m *Mission) {
+		m.jumpConflict = policy
+	}
+}
+
+// New creates a mission from stepList and opts, validating that step names
+// are unique and that every option refers to existing steps
+func New(stepList []StepDisposer, opts ...Option) (*Mission, error) {
+	m := NewMission(stepList)
+	for _, opt := range opts {
+		opt(m)
+	}
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// validate checks the step list and the configured options
+func (m *Mission) validate() error {
+	if len(m.stepList) == 0 {
+		return ErrNoSteps
+	}
+
+	seen := make(map[string]bool, len(m.stepList))
+	for _, step := range m.stepList {
+		if seen[step.StepName()] {
+			return fmt.Errorf("duplicate step name: %s", step.StepName())
+		}
+		seen[step.StepName()] = true
+	}
+
+	if err := m.defaultPolicy.validate(seen); err != nil {
+		return fmt.Errorf("default error policy: %w", err)
+	}
+	for name, policy := range m.stepPolicies {
+		if !seen[name] {
+			return fmt.Errorf("error policy for unknown step: %s", name)
+		}
+		if err := policy.validate(seen); err != nil {
+			return fmt.Errorf("error policy of step %s: %w", name, err)
+		}
+	}
+
+	if err := m.validateTimeouts(seen); err != nil {
+		return err
+	}
+
+	if err := m.validateFlow(); err != nil {
+		return err
+	}
+
+	if m.graph != nil {
+		if err := m.graph.validate(m.stepList); err != nil {
+			return err
+		}
+		for name, policy := range m.stepPolicies {
+			if policy.Action == ActionJump {
+				return fmt.Errorf("error policy of step %s: jumps are not supported by DAG missions", name)
+			}
+		}
+		if m.defaultPolicy.Action == ActionJump {
+			return errors.New("default error policy: jumps are not supported by DAG missions")
+		}
+	}
+	return nil
+}
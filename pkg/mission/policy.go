@@ -0,0 +1,118 @@
+package mission
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"lbox/pkg/clock"
+)
+
+// Action selects what the mission does when a step fails
+type Action int
+
+const (
+	// ActionFail stops the mission with the step error
+	ActionFail Action = iota
+	// ActionRetry runs the step again, failing the mission once attempts run out
+	ActionRetry
+	// ActionSkip continues with the next step
+	ActionSkip
+	// ActionJump continues with the step named by the policy target
+	ActionJump
+)
+
+// Backoff returns how long to wait before the given retry attempt, starting at 1
+type Backoff func(attempt int) time.Duration
+
+// ConstantBackoff waits d before every retry
+func ConstantBackoff(d time.Duration) Backoff {
+	return func(int) time.Duration {
+		return d
+	}
+}
+
+// ExponentialBackoff doubles the wait from base on every retry, capped at max
+func ExponentialBackoff(base, max time.Duration) Backoff {
+	return func(attempt int) time.Duration {
+		d := base
+		for i := 1; i < attempt && d < max; i++ {
+			d *= 2
+		}
+		if d > max {
+			d = max
+		}
+		return d
+	}
+}
+
+// ErrorPolicy describes how a failing step is handled
+type ErrorPolicy struct {
+	Action Action
+	// MaxAttempts bounds the number of runs of the step for ActionRetry,
+	// including the first one
+	MaxAttempts int
+	// Backoff spaces retries, no wait when nil
+	Backoff Backoff
+	// Target is the step to continue with for ActionJump
+	Target string
+}
+
+// Fail stops the mission when the step fails
+func Fail() ErrorPolicy {
+	return ErrorPolicy{Action: ActionFail}
+}
+
+// Retry runs the step up to maxAttempts times in total, waiting backoff
+// between attempts
+func Retry(maxAttempts int, backoff Backoff) ErrorPolicy {
+	return ErrorPolicy{Action: ActionRetry, MaxAttempts: maxAttempts, Backoff: backoff}
+}
+
+// Skip ignores the failure and continues with the next step
+func Skip() ErrorPolicy {
+	return ErrorPolicy{Action: ActionSkip}
+}
+
+// JumpTo continues with the named error-handling step
+func JumpTo(stepName string) ErrorPolicy {
+	return ErrorPolicy{Action: ActionJump, Target: stepName}
+}
+
+// validate checks the policy against the known step names
+func (p ErrorPolicy) validate(steps map[string]bool) error {
+	switch p.Action {
+	case ActionFail, ActionSkip:
+		return nil
+	case ActionRetry:
+		if p.MaxAttempts < 1 {
+			return errors.New("retry needs at least one attempt")
+		}
+		return nil
+	case ActionJump:
+		if !steps[p.Target] {
+			return fmt.Errorf("jump target not found: %s", p.Target)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown action: %d", p.Action)
+	}
+}
+
+// wait sleeps on c before the given retry attempt and reports false when ctx
+// is done first
+func (p ErrorPolicy) wait(ctx context.Context, c clock.TimerClock, attempt int) bool {
+	if p.Backoff == nil {
+		return ctx.Err() == nil
+	}
+
+	timer := c.NewTimer(p.Backoff(attempt))
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,223 @@
+package mission
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"lbox/pkg/clock"
+)
+
+var errStep = errors.New("step failed")
+
+// FlakyStep fails a number of times before succeeding and navigates on success
+type FlakyStep struct {
+	name     string
+	failures int
+	panics   bool
+	advance  bool
+	mu       sync.Mutex
+	runs     int
+}
+
+func (s *FlakyStep) StepName() string {
+	return s.name
+}
+
+func (s *FlakyStep) Dispose(m *Mission, _ []interface{}) error {
+	s.mu.Lock()
+	s.runs++
+	runs := s.runs
+	s.mu.Unlock()
+
+	if runs <= s.failures {
+		if s.panics {
+			panic("boom")
+		}
+		return errStep
+	}
+	if s.advance {
+		m.GoNext()
+	}
+	return nil
+}
+
+func (s *FlakyStep) Runs() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.runs
+}
+
+// waitResult waits for the mission to complete and returns its result
+func waitResult(t *testing.T, m *Mission) Result {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	result, err := m.Wait(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("Mission did not complete in time")
+	}
+	return result
+}
+
+func mustNew(t *testing.T, steps []StepDisposer, opts ...Option) *Mission {
+	t.Helper()
+	m, err := New(steps, opts...)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return m
+}
+
+func TestMission_FailPolicy(t *testing.T) {
+	step1 := &FlakyStep{name: "step1", failures: 1, advance: true}
+	step2 := &FlakyStep{name: "step2"}
+
+	m := mustNew(t, []StepDisposer{step1, step2})
+	m.Start()
+	result := waitResult(t, m)
+
+	if m.IsRunning() {
+		t.Error("Mission should stop when a step fails with the default policy")
+	}
+	if step2.Runs() != 0 {
+		t.Error("No further steps should run after a failure")
+	}
+
+	var stepErr *StepError
+	if result.Outcome != OutcomeFailed {
+		t.Errorf("Expected failed outcome, got %s", result.Outcome)
+	}
+	if !errors.As(result.Err, &stepErr) || stepErr.Step != "step1" || !errors.Is(result.Err, errStep) {
+		t.Errorf("Expected step1 error in result, got %v", result.Err)
+	}
+}
+
+func TestMission_RetryPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		failures    int
+		expectRuns  int
+		expectError bool
+	}{
+		{name: "Succeeds on retry", failures: 2, expectRuns: 3},
+		{name: "Attempts exhausted", failures: 5, expectRuns: 3, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step1 := &FlakyStep{name: "step1", failures: tt.failures, advance: true}
+			step2 := &FlakyStep{name: "step2", advance: true}
+
+			m := mustNew(t, []StepDisposer{step1, step2},
+				WithStepPolicy("step1", Retry(3, ConstantBackoff(time.Millisecond))))
+			m.Start()
+			result := waitResult(t, m)
+
+			if step1.Runs() != tt.expectRuns {
+				t.Errorf("Expected %d runs, got %d", tt.expectRuns, step1.Runs())
+			}
+
+			if tt.expectError {
+				var stepErr *StepError
+				if !errors.As(result.Err, &stepErr) || stepErr.Attempts != 3 {
+					t.Errorf("Expected error after 3 attempts, got %v", result.Err)
+				}
+			} else {
+				if result.Err != nil {
+					t.Errorf("Expected no error, got %v", result.Err)
+				}
+				if step2.Runs() != 1 {
+					t.Error("Mission should continue after a successful retry")
+				}
+			}
+		})
+	}
+}
+
+func TestMission_RetryBackoffClock(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	step := &FlakyStep{name: "step1", failures: 2}
+	m := mustNew(t, []StepDisposer{step},
+		WithClock(fake),
+		WithAutoAdvance(),
+		WithStepPolicy("step1", Retry(3, ExponentialBackoff(time.Minute, time.Hour))))
+	m.Start()
+
+	for attempt, backoff := range []time.Duration{time.Minute, 2 * time.Minute} {
+		fake.BlockUntil(1)
+		if runs := step.Runs(); runs != attempt+1 {
+			t.Fatalf("Expected %d runs before the backoff elapsed, got %d", attempt+1, runs)
+		}
+		fake.Advance(backoff)
+	}
+	result := waitResult(t, m)
+
+	if result.Outcome != OutcomeSucceeded || len(result.Path) != 1 || result.Path[0].Err != nil || result.Path[0].Duration != 3*time.Minute {
+		t.Errorf("Expected the step to succeed after 3 minutes of backoff, got %+v", result.Path)
+	}
+}
+
+func TestMission_SkipAndJumpPolicies(t *testing.T) {
+	step1 := &FlakyStep{name: "step1", failures: 1}
+	step2 := &FlakyStep{name: "step2", failures: 1, panics: true}
+	step3 := &FlakyStep{name: "step3"}
+	fallback := &FlakyStep{name: "recover"}
+
+	m := mustNew(t, []StepDisposer{step1, step2, step3, fallback},
+		WithErrorPolicy(Skip()),
+		WithStepPolicy("step2", JumpTo("recover")))
+	m.Start()
+	waitPath(t, m, 3)
+	m.Stop()
+	result := waitResult(t, m)
+
+	if step2.Runs() != 1 || step3.Runs() != 0 || fallback.Runs() != 1 {
+		t.Errorf("Unexpected runs: step2=%d step3=%d recover=%d", step2.Runs(), step3.Runs(), fallback.Runs())
+	}
+
+	if len(result.StepErrors) != 2 {
+		t.Fatalf("Expected 2 recorded step errors, got %d", len(result.StepErrors))
+	}
+	var panicErr *PanicError
+	if !errors.As(result.StepErrors[1], &panicErr) {
+		t.Errorf("Expected panic to be recorded as PanicError, got %v", result.StepErrors[1])
+	}
+}
+
+func TestNew_Validation(t *testing.T) {
+	step1 := NewMockStep("step1")
+	step2 := NewMockStep("step2")
+
+	tests := []struct {
+		name  string
+		steps []StepDisposer
+		opts  []Option
+	}{
+		{name: "No steps"},
+		{name: "Duplicate names", steps: []StepDisposer{step1, NewMockStep("step1")}},
+		{name: "Unknown policy step", steps: []StepDisposer{step1}, opts: []Option{WithStepPolicy("nope", Skip())}},
+		{name: "Unknown jump target", steps: []StepDisposer{step1}, opts: []Option{WithErrorPolicy(JumpTo("nope"))}},
+		{name: "Retry without attempts", steps: []StepDisposer{step1, step2}, opts: []Option{WithStepPolicy("step2", Retry(0, nil))}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.steps, tt.opts...); err == nil {
+				t.Error("Expected validation error")
+			}
+		})
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(10*time.Millisecond, 50*time.Millisecond)
+	expected := []time.Duration{10, 20, 40, 50, 50}
+	for i, want := range expected {
+		if got := backoff(i + 1); got != want*time.Millisecond {
+			t.Errorf("Attempt %d: expected %v, got %v", i+1, want*time.Millisecond, got)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package mission
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Outcome tells how a mission run ended
+type Outcome int
+
+const (
+	// OutcomeUnfinished means the mission has not completed yet
+	OutcomeUnfinished Outcome = iota
+	// OutcomeSucceeded means the mission moved past its last step
+	OutcomeSucceeded
+	// OutcomeFailed means a step failure stopped the mission
+	OutcomeFailed
+	// OutcomeStopped means the mission was stopped or its context cancelled
+	OutcomeStopped
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeSucceeded:
+		return "succeeded"
+	case OutcomeFailed:
+		return "failed"
+	case OutcomeStopped:
+		return "stopped"
+	default:
+		return "unfinished"
+	}
+}
+
+// StepRecord describes one execution of a step, retries included
+type StepRecord struct {
+	Name     string
+	Started  time.Time
+	Duration time.Duration
+	Attempts int
+	// Err is the error of the last attempt, nil when the step succeeded
+	Err error
+	// Nested is the path of the sub-mission run by the last attempt of a
+	// SubMission step
+	Nested []StepRecord
+}
+
+// TimedOut reports whether the last attempt of the step exceeded its timeout
+func (r StepRecord) TimedOut() bool {
+	return errors.Is(r.Err, ErrStepTimeout)
+}
+
+// Result summarizes a mission run
+type Result struct {
+	Outcome Outcome
+	// Err is the error that ended the mission, nil on success
+	Err error
+	// Path lists the executed steps in order
+	Path []StepRecord
+	// StepErrors lists every step failure, including handled ones
+	StepErrors []*StepError
+	// Outputs is a snapshot of the mission data, read it with Key.Output
+	Outputs map[string]interface{}
+	// Compensated lists the steps rolled back, in the order of compensation
+	Compensated []string
+	// CompensationErrors lists the compensations that failed
+	CompensationErrors []*StepError
+}
+
+// StepNames returns the names of the executed steps in order
+func (r Result) StepNames() []string {
+	names := make([]string, len(r.Path))
+	for i, record := range r.Path {
+		names[i] = record.Name
+	}
+	return names
+}
+
+// Result returns the outcome of the current or last run
+func (m *Mission) Result() Result {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.result()
+}
+
+// result snapshots the run
+// Note: This method assumes the caller holds the lock
+func (m *Mission) result() Result {
+	return Result{
+		Outcome:    m.outcome,
+		Err:        m.err,
+		Path:       append([]StepRecord(nil), m.path...),
+		StepErrors: append([]*StepError(nil), m.stepErrors...),
+		Outputs:    m.data.Snapshot(),
+
+		Compensated:        append([]string(nil), m.compensated...),
+		CompensationErrors: append([]*StepError(nil), m.compensationErrors...),
+	}
+}
+
+// Done returns a channel closed when the current or next run completes and
+// no step is executing anymore
+func (m *Mission) Done() <-chan struct{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.done
+}
+
+// Wait blocks until the run completes or ctx is done. It returns the result
+// together with its error, or ctx.Err() when ctx ends first
+func (m *Mission) Wait(ctx context.Context) (Result, error) {
+	select {
+	case <-m.Done():
+		result := m.Result()
+		return result, result.Err
+	case <-ctx.Done():
+		return m.Result(), ctx.Err()
+	}
+}
+
+// OnComplete registers fn to be called with the result of every run. Callbacks
+// run on the executor goroutine right after Done is closed
+func (m *Mission) OnComplete(fn func(Result)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onComplete = append(m.onComplete, fn)
+}
+
+// WithOnComplete registers a completion callback, see OnComplete
+func WithOnComplete(fn func(Result)) Option {
+	return func(m *Mission) {
+		m.onComplete = append(m.onComplete, fn)
+	}
+}
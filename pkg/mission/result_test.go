@@ -0,0 +1,93 @@
+package mission
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMission_WaitAndResult(t *testing.T) {
+	step1 := &FlakyStep{name: "step1", advance: true}
+	step2 := &FlakyStep{name: "step2", advance: true}
+
+	completed := make(chan Result, 1)
+	m := mustNew(t, []StepDisposer{step1, step2}, WithOnComplete(func(r Result) {
+		completed <- r
+	}))
+	m.Start()
+
+	result := waitResult(t, m)
+	if result.Outcome != OutcomeSucceeded || result.Err != nil {
+		t.Fatalf("Expected success, got %s: %v", result.Outcome, result.Err)
+	}
+
+	names := result.StepNames()
+	if len(names) != 2 || names[0] != "step1" || names[1] != "step2" {
+		t.Errorf("Unexpected path: %v", names)
+	}
+	for _, record := range result.Path {
+		if record.Started.IsZero() || record.Duration < 0 || record.Attempts != 1 {
+			t.Errorf("Unexpected record: %+v", record)
+		}
+	}
+
+	select {
+	case r := <-completed:
+		if r.Outcome != OutcomeSucceeded {
+			t.Errorf("OnComplete got outcome %s", r.Outcome)
+		}
+	case <-time.After(time.Second):
+		t.Error("OnComplete was not called")
+	}
+
+	select {
+	case <-m.Done():
+	default:
+		t.Error("Done should be closed after completion")
+	}
+}
+
+func TestMission_WaitStopped(t *testing.T) {
+	step1 := NewContextStep("step1")
+	m := NewMission([]StepDisposer{step1})
+	m.Start()
+	<-step1.started
+
+	// Wait gives up when its own context ends first
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if _, err := m.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+
+	m.Stop()
+	result := waitResult(t, m)
+	if result.Outcome != OutcomeStopped || !errors.Is(result.Err, context.Canceled) {
+		t.Errorf("Expected stopped outcome, got %s: %v", result.Outcome, result.Err)
+	}
+	if len(result.Path) != 1 || result.Path[0].Err == nil {
+		t.Errorf("Expected cancelled step in path, got %+v", result.Path)
+	}
+}
+
+func TestMission_Restart(t *testing.T) {
+	step1 := &FlakyStep{name: "step1", advance: true}
+	m := NewMission([]StepDisposer{step1})
+
+	m.Start()
+	first := m.Done()
+	waitResult(t, m)
+
+	m.Start()
+	if m.Done() == first {
+		t.Fatal("A new run should get a new Done channel")
+	}
+	result := waitResult(t, m)
+	if result.Outcome != OutcomeSucceeded || len(result.Path) != 1 {
+		t.Errorf("Expected a fresh successful result, got %+v", result)
+	}
+	if step1.Runs() != 2 {
+		t.Errorf("Expected step1 to run once per start, got %d", step1.Runs())
+	}
+}
@@ -0,0 +1,219 @@
+package mission
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrRunnerClosed is returned by Submit after Shutdown was called
+var ErrRunnerClosed = errors.New("runner is shut down")
+
+// ErrQueueFull is returned by Submit when the runner queue is at capacity
+var ErrQueueFull = errors.New("runner queue is full")
+
+// RunnerOptions configures a Runner, zero values select the defaults
+type RunnerOptions struct {
+	// Workers bounds the missions running at the same time, defaults to 4
+	Workers int
+	// QueueSize bounds the missions waiting to run, zero means no limit
+	QueueSize int
+	// TypeLimits bounds the running missions per type, see OfType. Types
+	// without a limit only share the Workers bound
+	TypeLimits map[string]int
+}
+
+// SubmitOption configures a mission submitted to a Runner
+type SubmitOption func(j *job)
+
+// WithPriority runs the mission before queued missions of lower priority.
+// Missions of equal priority run in submission order. The default is zero
+func WithPriority(priority int) SubmitOption {
+	return func(j *job) {
+		j.priority = priority
+	}
+}
+
+// OfType assigns the mission to a type limited by RunnerOptions.TypeLimits
+func OfType(missionType string) SubmitOption {
+	return func(j *job) {
+		j.missionType = missionType
+	}
+}
+
+// RunnerStats is a snapshot of the load of a Runner
+type RunnerStats struct {
+	Queued        int
+	Running       int
+	QueuedByType  map[string]int
+	RunningByType map[string]int
+}
+
+// job is a mission waiting in or taken from the runner queue
+type job struct {
+	mission     *Mission
+	priority    int
+	missionType string
+	seq         uint64
+}
+
+// Runner executes submitted missions on a bounded number of workers, picking
+// the queued mission of highest priority whose type is below its limit
+type Runner struct {
+	options RunnerOptions
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	queue         []*job
+	running       map[*job]bool
+	runningByType map[string]int
+	seq           uint64
+	closed        bool
+	idle          chan struct{} // closed when closed and nothing is left
+	mu            sync.Mutex
+}
+
+// NewRunner creates a runner ready to accept missions
+func NewRunner(options RunnerOptions) *Runner {
+	if options.Workers <= 0 {
+		options.Workers = 4
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Runner{
+		options:       options,
+		ctx:           ctx,
+		cancel:        cancel,
+		running:       make(map[*job]bool),
+		runningByType: make(map[string]int),
+		idle:          make(chan struct{}),
+	}
+}
+
+// Submit queues m to be started by the runner. Use the Done, Wait or
+// OnComplete methods of m to learn about its completion. A running mission is
+// rejected with ErrAlreadyRunning, one started elsewhere while queued is
+// skipped by the runner
+func (r *Runner) Submit(m *Mission, opts ...SubmitOption) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return ErrRunnerClosed
+	}
+	if m.IsRunning() {
+		return ErrAlreadyRunning
+	}
+	if r.options.QueueSize > 0 && len(r.queue) >= r.options.QueueSize {
+		return ErrQueueFull
+	}
+
+	j := &job{mission: m, seq: r.seq}
+	r.seq++
+	for _, opt := range opts {
+		opt(j)
+	}
+	r.queue = append(r.queue, j)
+	r.dispatch()
+	return nil
+}
+
+// Stats returns the current queue depth and running counts
+func (r *Runner) Stats() RunnerStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := RunnerStats{
+		Queued:        len(r.queue),
+		Running:       len(r.running),
+		QueuedByType:  make(map[string]int),
+		RunningByType: make(map[string]int),
+	}
+	for _, j := range r.queue {
+		stats.QueuedByType[j.missionType]++
+	}
+	for missionType, n := range r.runningByType {
+		if n > 0 {
+			stats.RunningByType[missionType] = n
+		}
+	}
+	return stats
+}
+
+// Shutdown stops accepting missions and waits until the queued and running
+// missions completed. When ctx ends first the queued missions are dropped, the
+// running ones are stopped and ctx.Err() is returned without waiting for
+// steps that ignore their context
+func (r *Runner) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	if !r.closed {
+		r.closed = true
+		r.checkIdle()
+	}
+	idle := r.idle
+	r.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+	}
+
+	r.mu.Lock()
+	r.queue = nil
+	r.checkIdle()
+	r.mu.Unlock()
+	r.cancel()
+	return ctx.Err()
+}
+
+// dispatch starts queued missions while workers are available
+// Note: This method assumes the caller holds the lock
+func (r *Runner) dispatch() {
+	for len(r.running) < r.options.Workers {
+		next := -1
+		for i, j := range r.queue {
+			if limit, ok := r.options.TypeLimits[j.missionType]; ok && r.runningByType[j.missionType] >= limit {
+				continue
+			}
+			if next < 0 || j.priority > r.queue[next].priority {
+				next = i
+			}
+		}
+		if next < 0 {
+			return
+		}
+
+		j := r.queue[next]
+		r.queue = append(r.queue[:next], r.queue[next+1:]...)
+		r.running[j] = true
+		r.runningByType[j.missionType]++
+		go r.run(j)
+	}
+}
+
+// run executes one mission on a worker
+func (r *Runner) run(j *job) {
+	if err := j.mission.StartContext(r.ctx); err == nil {
+		<-j.mission.Done()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.running, j)
+	r.runningByType[j.missionType]--
+	r.dispatch()
+	r.checkIdle()
+}
+
+// checkIdle closes idle once the runner is shut down and empty
+// Note: This method assumes the caller holds the lock
+func (r *Runner) checkIdle() {
+	if !r.closed || len(r.queue) > 0 || len(r.running) > 0 {
+		return
+	}
+	select {
+	case <-r.idle:
+	default:
+		close(r.idle)
+	}
+}
@@ -0,0 +1,170 @@
+package mission
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gatedMission returns a mission whose only step records name and then blocks
+// until gate is closed
+func gatedMission(t *testing.T, name string, gate chan struct{}, started chan<- string) *Mission {
+	return mustNew(t, []StepDisposer{&FuncStep{name: name, fn: func(*Mission) error {
+		started <- name
+		<-gate
+		return nil
+	}}}, WithAutoAdvance())
+}
+
+// waitStats polls the runner until cond holds
+func waitStats(t *testing.T, r *Runner, cond func(RunnerStats) bool) RunnerStats {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		stats := r.Stats()
+		if cond(stats) {
+			return stats
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Runner did not reach the expected state, got %+v", stats)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRunner_WorkersAndPriority(t *testing.T) {
+	r := NewRunner(RunnerOptions{Workers: 1})
+	gate := make(chan struct{})
+	started := make(chan string, 4)
+
+	first := gatedMission(t, "first", gate, started)
+	if err := r.Submit(first); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	<-started
+
+	for _, sub := range []struct {
+		name     string
+		priority int
+	}{{"low", 0}, {"high", 10}, {"medium", 5}} {
+		if err := r.Submit(gatedMission(t, sub.name, gate, started), WithPriority(sub.priority)); err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+	}
+	if stats := r.Stats(); stats.Running != 1 || stats.Queued != 3 {
+		t.Errorf("Expected 1 running and 3 queued, got %+v", stats)
+	}
+
+	close(gate)
+	var order []string
+	for i := 0; i < 3; i++ {
+		order = append(order, <-started)
+	}
+	if !reflect.DeepEqual(order, []string{"high", "medium", "low"}) {
+		t.Errorf("Expected priority order, got %v", order)
+	}
+	if err := r.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown failed: %v", err)
+	}
+	if err := r.Submit(first); !errors.Is(err, ErrRunnerClosed) {
+		t.Errorf("Expected ErrRunnerClosed, got %v", err)
+	}
+}
+
+func TestRunner_TypeLimits(t *testing.T) {
+	r := NewRunner(RunnerOptions{Workers: 3, TypeLimits: map[string]int{"report": 1}})
+	gate := make(chan struct{})
+	started := make(chan string, 3)
+
+	for _, sub := range []struct{ name, missionType string }{
+		{"report-1", "report"},
+		{"report-2", "report"},
+		{"sync", "sync"},
+	} {
+		if err := r.Submit(gatedMission(t, sub.name, gate, started), OfType(sub.missionType)); err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+	}
+
+	stats := waitStats(t, r, func(s RunnerStats) bool { return s.Running == 2 })
+	if stats.RunningByType["report"] != 1 || stats.QueuedByType["report"] != 1 || stats.RunningByType["sync"] != 1 {
+		t.Errorf("Expected the report limit to hold back one mission, got %+v", stats)
+	}
+
+	close(gate)
+	if err := r.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown failed: %v", err)
+	}
+	if stats := r.Stats(); stats.Running != 0 || stats.Queued != 0 {
+		t.Errorf("Expected an empty runner after Shutdown, got %+v", stats)
+	}
+}
+
+func TestRunner_QueueFullAndForcedShutdown(t *testing.T) {
+	r := NewRunner(RunnerOptions{Workers: 1, QueueSize: 1})
+	started := make(chan string, 2)
+	var stopped sync.WaitGroup
+	stopped.Add(1)
+
+	blocking := mustNew(t, []StepDisposer{&ctxFuncStep{name: "block", fn: func(ctx context.Context) error {
+		started <- "block"
+		<-ctx.Done()
+		stopped.Done()
+		return ctx.Err()
+	}}})
+	if err := r.Submit(blocking); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	<-started
+	if err := r.Submit(blocking); !errors.Is(err, ErrAlreadyRunning) {
+		t.Errorf("Expected ErrAlreadyRunning, got %v", err)
+	}
+
+	queued := gatedMission(t, "queued", make(chan struct{}), started)
+	if err := r.Submit(queued); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	if err := r.Submit(gatedMission(t, "overflow", nil, started)); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected ErrQueueFull, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := r.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected forced shutdown to report the deadline, got %v", err)
+	}
+	stopped.Wait()
+	<-blocking.Done()
+	if blocking.Result().Outcome != OutcomeStopped || queued.IsRunning() {
+		t.Errorf("Expected running mission stopped and queued one dropped")
+	}
+}
+
+func TestRunner_ForcedShutdownIgnoresHungStep(t *testing.T) {
+	r := NewRunner(RunnerOptions{Workers: 1})
+	release := make(chan struct{})
+	defer close(release)
+
+	if err := r.Submit(mustNew(t, []StepDisposer{hungStep("hung", release)})); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	waitStats(t, r, func(s RunnerStats) bool { return s.Running == 1 })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	returned := make(chan error, 1)
+	go func() {
+		returned <- r.Shutdown(ctx)
+	}()
+	select {
+	case err := <-returned:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected the deadline error, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Shutdown blocked past its context on a step ignoring cancellation")
+	}
+}
@@ -0,0 +1,115 @@
+package mission
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Signal delivers payload to the step waiting for the named signal, or keeps
+// it until a WaitForSignal step asks for it. Kept signals are part of the
+// checkpoint of the mission
+func (m *Mission) Signal(name string, payload interface{}) error {
+	m.mu.Lock()
+	if !m.running {
+		m.mu.Unlock()
+		return ErrNotRunning
+	}
+	if waiters := m.waiters[name]; len(waiters) > 0 {
+		m.waiters[name] = waiters[1:]
+		waiters[0] <- payload
+		m.mu.Unlock()
+		return nil
+	}
+	m.signals[name] = append(m.signals[name], payload)
+	running := m.lastRunning
+	m.mu.Unlock()
+
+	if !m.checkpoint(running...) {
+		return fmt.Errorf("signal %s could not be saved", name)
+	}
+	return nil
+}
+
+// waitForSignal is a step parking the mission until a signal arrives
+type waitForSignal struct {
+	name    string
+	signal  string
+	timeout time.Duration
+}
+
+// WaitForSignal returns a step that completes when the named signal is
+// delivered with Signal, storing its payload in the mission data under the
+// signal name. With a positive timeout the step fails with ErrStepTimeout
+// when no signal arrives in time, to be handled by its error policy. The
+// deadline is kept in checkpoints, so a resumed mission does not wait longer
+func WaitForSignal(name, signal string, timeout time.Duration) StepDisposer {
+	return &waitForSignal{name: name, signal: signal, timeout: timeout}
+}
+
+func (s *waitForSignal) StepName() string {
+	return s.name
+}
+
+func (s *waitForSignal) Dispose(m *Mission, tags []interface{}) error {
+	return s.DisposeContext(context.Background(), m, tags)
+}
+
+func (s *waitForSignal) DisposeContext(ctx context.Context, m *Mission, _ []interface{}) error {
+	m.mu.Lock()
+	if payloads := m.signals[s.signal]; len(payloads) > 0 {
+		m.signals[s.signal] = payloads[1:]
+		m.mu.Unlock()
+		m.data.Set(s.signal, payloads[0])
+		return nil
+	}
+
+	var expired <-chan time.Time
+	started := false
+	if s.timeout > 0 {
+		deadline, ok := m.waitDeadlines[s.name]
+		if !ok {
+			deadline = m.clock.Now().Add(s.timeout)
+			m.waitDeadlines[s.name] = deadline
+			started = true
+		}
+		timer := m.clock.NewTimer(deadline.Sub(m.clock.Now()))
+		defer timer.Stop()
+		expired = timer.C()
+	}
+	waiter := make(chan interface{}, 1)
+	m.waiters[s.signal] = append(m.waiters[s.signal], waiter)
+	running := m.lastRunning
+	m.mu.Unlock()
+
+	// Save the new deadline so a resumed mission keeps it
+	if started {
+		m.checkpoint(running...)
+	}
+
+	var err error
+	select {
+	case payload := <-waiter:
+		m.data.Set(s.signal, payload)
+	case <-expired:
+		err = fmt.Errorf("%w: no %s signal after %s", ErrStepTimeout, s.signal, s.timeout)
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.waitDeadlines, s.name)
+	if err == nil {
+		return nil
+	}
+	for i, w := range m.waiters[s.signal] {
+		if w == waiter {
+			m.waiters[s.signal] = append(m.waiters[s.signal][:i:i], m.waiters[s.signal][i+1:]...)
+			return err
+		}
+	}
+	// The signal was delivered while giving up
+	m.data.Set(s.signal, <-waiter)
+	return nil
+}
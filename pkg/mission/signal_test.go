@@ -0,0 +1,149 @@
+package mission
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"lbox/pkg/clock"
+)
+
+// waitForWaiter blocks until a step of m waits for the named signal
+func waitForWaiter(t *testing.T, m *Mission, signal string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		m.mu.Lock()
+		waiting := len(m.waiters[signal]) > 0
+		m.mu.Unlock()
+		if waiting {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("No step waits for %s", signal)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestMission_WaitForSignal(t *testing.T) {
+	m := mustNew(t, []StepDisposer{
+		namedStep("submit", nil),
+		WaitForSignal("approval", keyApproved.Name(), 0),
+		namedStep("publish", nil),
+	}, WithAutoAdvance())
+
+	if err := m.Signal(keyApproved.Name(), true); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("Expected ErrNotRunning before start, got %v", err)
+	}
+
+	m.Start()
+	waitForWaiter(t, m, keyApproved.Name())
+	if err := m.Signal(keyApproved.Name(), true); err != nil {
+		t.Fatalf("Signal failed: %v", err)
+	}
+	result := waitResult(t, m)
+
+	if result.Outcome != OutcomeSucceeded {
+		t.Fatalf("Expected success, got %s (%v)", result.Outcome, result.Err)
+	}
+	if approved, ok := keyApproved.Output(result); !ok || !approved {
+		t.Errorf("Expected signal payload in the data, got %v (%v)", approved, ok)
+	}
+}
+
+func TestMission_SignalBeforeWait(t *testing.T) {
+	release := make(chan struct{})
+	m := mustNew(t, []StepDisposer{
+		&FuncStep{name: "work", fn: func(*Mission) error {
+			<-release
+			return nil
+		}},
+		WaitForSignal("approval", keyApproved.Name(), time.Second),
+	}, WithAutoAdvance())
+	m.Start()
+
+	if err := m.Signal(keyApproved.Name(), true); err != nil {
+		t.Fatalf("Signal failed: %v", err)
+	}
+	close(release)
+	result := waitResult(t, m)
+
+	if result.Outcome != OutcomeSucceeded {
+		t.Errorf("Expected early signal to be kept for the step, got %s (%v)", result.Outcome, result.Err)
+	}
+}
+
+func TestMission_SignalTimeout(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	m := mustNew(t, []StepDisposer{
+		WaitForSignal("approval", keyApproved.Name(), time.Hour),
+		namedStep("escalate", func(m *Mission) { m.GoNext() }),
+	}, WithClock(fake), WithStepPolicy("approval", JumpTo("escalate")))
+	m.Start()
+	fake.BlockUntil(1)
+	fake.Advance(time.Hour)
+	result := waitResult(t, m)
+
+	if result.Outcome != OutcomeSucceeded {
+		t.Fatalf("Expected escalation to complete the mission, got %s (%v)", result.Outcome, result.Err)
+	}
+	if !result.Path[0].TimedOut() || result.Path[1].Name != "escalate" {
+		t.Errorf("Expected timed out wait followed by escalate, got %+v", result.Path)
+	}
+}
+
+func TestMission_SignalSurvivesCheckpoint(t *testing.T) {
+	store, snapshot := NewMemoryStore(), NewMemoryStore()
+	ctx := context.Background()
+	keyComment := NewKey[string]("comment")
+	steps := func() []StepDisposer {
+		return []StepDisposer{
+			WaitForSignal("approval", keyApproved.Name(), time.Hour),
+			WaitForSignal("feedback", keyComment.Name(), 0),
+		}
+	}
+
+	m := mustNew(t, steps(), WithAutoAdvance(), WithCheckpoint(store, "review"))
+	m.Start()
+	waitForWaiter(t, m, keyApproved.Name())
+	if err := m.Signal(keyComment.Name(), "looks good"); err != nil {
+		t.Fatalf("Signal failed: %v", err)
+	}
+
+	// Crash while the approval is pending
+	if err := crash(ctx, m, store, snapshot); err != nil {
+		t.Fatalf("Crash failed: %v", err)
+	}
+	<-m.Done()
+	cp, _ := snapshot.Load(ctx, "review")
+	deadline, ok := cp.Deadlines["approval"]
+	if !ok || len(cp.Signals[keyComment.Name()]) != 1 {
+		t.Fatalf("Expected deadline and kept signal in the checkpoint, got %+v", cp)
+	}
+
+	resumed, err := Restore(ctx, snapshot, "review", steps(), WithAutoAdvance())
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	resumed.Start()
+	waitForWaiter(t, resumed, keyApproved.Name())
+	resumed.mu.Lock()
+	kept := resumed.waitDeadlines["approval"]
+	resumed.mu.Unlock()
+	if !kept.Equal(deadline) {
+		t.Errorf("Expected the original deadline %v, got %v", deadline, kept)
+	}
+
+	if err := resumed.Signal(keyApproved.Name(), true); err != nil {
+		t.Fatalf("Signal failed: %v", err)
+	}
+	result := waitResult(t, resumed)
+	if result.Outcome != OutcomeSucceeded {
+		t.Fatalf("Expected resumed mission to succeed, got %s (%v)", result.Outcome, result.Err)
+	}
+	if comment, _ := keyComment.Output(result); comment != "looks good" {
+		t.Errorf("Expected restored signal payload, got %q", comment)
+	}
+}
@@ -0,0 +1,166 @@
+package mission
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrCheckpointNotFound is returned by Store.Load for unknown mission IDs
+var ErrCheckpointNotFound = errors.New("checkpoint not found")
+
+// Checkpoint is the persisted progress of a mission
+type Checkpoint struct {
+	ID      string  `json:"id"`
+	Outcome Outcome `json:"outcome"`
+	// Path lists the executed steps in order
+	Path []PathEntry `json:"path,omitempty"`
+	// Current is the current step of a linear mission
+	Current string `json:"current,omitempty"`
+	// History lists the steps entered by a linear mission, used by Back
+	History []string `json:"history,omitempty"`
+	// Running lists the steps that were executing when the checkpoint was saved
+	Running []string `json:"running,omitempty"`
+	// Pending holds the navigation commands queued after Current
+	Pending []PendingCommand `json:"pending,omitempty"`
+	// Signals holds the JSON encoded payloads not yet waited for
+	Signals map[string][]json.RawMessage `json:"signals,omitempty"`
+	// Deadlines holds the timeouts of WaitForSignal steps by step name
+	Deadlines map[string]time.Time `json:"deadlines,omitempty"`
+	// Data is the JSON encoded mission data
+	Data      map[string]json.RawMessage `json:"data,omitempty"`
+	UpdatedAt time.Time                  `json:"updatedAt"`
+}
+
+// PathEntry is a persisted StepRecord
+type PathEntry struct {
+	Name     string `json:"name"`
+	Attempts int    `json:"attempts"`
+	Error    string `json:"error,omitempty"`
+}
+
+// PendingCommand is a persisted navigation request
+type PendingCommand struct {
+	Kind   string `json:"kind"`
+	Target string `json:"target,omitempty"`
+}
+
+// Store persists mission checkpoints
+type Store interface {
+	Save(ctx context.Context, cp Checkpoint) error
+	Load(ctx context.Context, id string) (Checkpoint, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// MemoryStore keeps checkpoints in memory, encoded like a durable store would
+type MemoryStore struct {
+	checkpoints map[string][]byte
+	mu          sync.Mutex
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{checkpoints: make(map[string][]byte)}
+}
+
+func (s *MemoryStore) Save(_ context.Context, cp Checkpoint) error {
+	encoded, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkpoints[cp.ID] = encoded
+	return nil
+}
+
+func (s *MemoryStore) Load(_ context.Context, id string) (Checkpoint, error) {
+	s.mu.Lock()
+	encoded, ok := s.checkpoints[id]
+	s.mu.Unlock()
+
+	var cp Checkpoint
+	if !ok {
+		return cp, fmt.Errorf("%w: %s", ErrCheckpointNotFound, id)
+	}
+	err := json.Unmarshal(encoded, &cp)
+	return cp, err
+}
+
+func (s *MemoryStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.checkpoints, id)
+	return nil
+}
+
+// FileStore keeps one JSON file per mission in a directory
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a store writing into dir, creating it when missing
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Save writes the checkpoint atomically through a temporary file
+func (s *FileStore) Save(_ context.Context, cp Checkpoint) error {
+	encoded, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".checkpoint-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(encoded); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(cp.ID))
+}
+
+func (s *FileStore) Load(_ context.Context, id string) (Checkpoint, error) {
+	var cp Checkpoint
+	encoded, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return cp, fmt.Errorf("%w: %s", ErrCheckpointNotFound, id)
+	}
+	if err != nil {
+		return cp, err
+	}
+	err = json.Unmarshal(encoded, &cp)
+	return cp, err
+}
+
+func (s *FileStore) Delete(_ context.Context, id string) error {
+	err := os.Remove(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *FileStore) path(id string) string {
+	return filepath.Join(s.dir, url.PathEscape(id)+".json")
+}
@@ -0,0 +1,70 @@
+package mission
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func testStore(t *testing.T, store Store) {
+	t.Helper()
+	ctx := context.Background()
+
+	cp := Checkpoint{
+		ID:      "orders/42",
+		Path:    []PathEntry{{Name: "a", Attempts: 1}},
+		Current: "a",
+		Pending: []PendingCommand{{Kind: "jump", Target: "c"}},
+		Data:    map[string]json.RawMessage{"count": json.RawMessage("3")},
+	}
+	if err := store.Save(ctx, cp); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := store.Load(ctx, "orders/42")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.Current != "a" || len(loaded.Path) != 1 || loaded.Pending[0].Target != "c" || string(loaded.Data["count"]) != "3" {
+		t.Errorf("Loaded checkpoint differs: %+v", loaded)
+	}
+
+	if err := store.Delete(ctx, "orders/42"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := store.Load(ctx, "orders/42"); !errors.Is(err, ErrCheckpointNotFound) {
+		t.Errorf("Expected ErrCheckpointNotFound after Delete, got %v", err)
+	}
+	if err := store.Delete(ctx, "orders/42"); err != nil {
+		t.Errorf("Deleting a missing checkpoint should succeed, got %v", err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestFileStore(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	testStore(t, store)
+}
+
+func TestKey_DecodesRestoredValues(t *testing.T) {
+	d := NewData()
+	d.Set("total", json.RawMessage("12.5"))
+
+	if got, ok := keyTotal.Get(d); !ok || got != 12.5 {
+		t.Errorf("Expected decoded 12.5, got %v (%v)", got, ok)
+	}
+	if value, _ := d.Get("total"); value != 12.5 {
+		t.Errorf("Expected decoded value to replace the raw one, got %#v", value)
+	}
+	d.Set("orderID", json.RawMessage("17"))
+	if _, ok := keyOrderID.Get(d); ok {
+		t.Error("Raw value of another type should not decode")
+	}
+}
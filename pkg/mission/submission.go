@@ -0,0 +1,117 @@
+package mission
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// SubOption configures a step created with SubMission
+type SubOption func(s *subMission)
+
+// WithScopedData gives the sub-mission its own data store instead of the data
+// of the parent. The values named by inputs are copied in before the run and
+// the values named by outputs are copied back after a successful run
+func WithScopedData(inputs, outputs []string) SubOption {
+	return func(s *subMission) {
+		s.scoped = true
+		s.inputs = inputs
+		s.outputs = outputs
+	}
+}
+
+// subMission is a step running a nested mission
+type subMission struct {
+	name    string
+	build   func() (*Mission, error)
+	scoped  bool
+	inputs  []string
+	outputs []string
+}
+
+// SubMission returns a step that runs the mission created by build to
+// completion. build is called for every execution and must return a new
+// mission. By default the sub-mission shares the data of the parent, see
+// WithScopedData. Cancelling the parent cancels the sub-mission, a failed or
+// stopped sub-mission fails the step. The nested path is recorded in
+// StepRecord.Nested and the events of the sub-mission reach the observers of
+// the parent with the step name prepended to Event.Path
+func SubMission(name string, build func() (*Mission, error), opts ...SubOption) StepDisposer {
+	s := &subMission{name: name, build: build}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *subMission) StepName() string {
+	return s.name
+}
+
+func (s *subMission) Dispose(m *Mission, tags []interface{}) error {
+	return s.DisposeContext(context.Background(), m, tags)
+}
+
+func (s *subMission) DisposeContext(ctx context.Context, m *Mission, _ []interface{}) error {
+	child, err := s.build()
+	if err != nil {
+		return fmt.Errorf("sub-mission %s: %w", s.name, err)
+	}
+
+	if s.scoped {
+		for _, name := range s.inputs {
+			if value, ok := m.data.Get(name); ok {
+				child.data.Set(name, value)
+			}
+		}
+	} else {
+		child.data = m.data
+	}
+	child.AddObserver(ObserverFunc(func(_ *Mission, e Event) {
+		e.Path = append([]string{s.name}, e.Path...)
+		m.notify(e)
+	}))
+
+	if err := child.StartContext(ctx); err != nil {
+		return fmt.Errorf("sub-mission %s: %w", s.name, err)
+	}
+	<-child.Done()
+	result := child.Result()
+
+	if sink, ok := ctx.Value(nestedKey{}).(*nestedSink); ok {
+		sink.set(result.Path)
+	}
+	if result.Outcome != OutcomeSucceeded {
+		return fmt.Errorf("sub-mission %s %s: %w", s.name, result.Outcome, result.Err)
+	}
+	if s.scoped {
+		for _, name := range s.outputs {
+			if value, ok := child.data.Get(name); ok {
+				m.data.Set(name, value)
+			}
+		}
+	}
+	return nil
+}
+
+// nestedKey carries the sink for the path of a sub-mission run by a step
+type nestedKey struct{}
+
+// nestedSink receives the path of a sub-mission. A step abandoned after a
+// timeout may still write to it
+type nestedSink struct {
+	path []StepRecord
+	mu   sync.Mutex
+}
+
+func (s *nestedSink) set(path []StepRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.path = path
+}
+
+func (s *nestedSink) get() []StepRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.path
+}
@@ -0,0 +1,115 @@
+package mission
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// validateAndLock builds the reusable child mission of the sub-mission tests
+func validateAndLock(lockErr error) func() (*Mission, error) {
+	return func() (*Mission, error) {
+		return New([]StepDisposer{
+			namedStep("validate", func(m *Mission) {
+				keyTotal.Set(m.Data(), float64(len(keyOrderID.GetOr(m.Data(), ""))))
+			}),
+			&FuncStep{name: "lock", fn: func(m *Mission) error {
+				keyCount.Set(m.Data(), 1)
+				return lockErr
+			}},
+		}, WithAutoAdvance())
+	}
+}
+
+func TestMission_SubMissionSharedData(t *testing.T) {
+	log := &eventLog{}
+	m := mustNew(t, []StepDisposer{
+		namedStep("prepare", nil),
+		SubMission("validate-and-lock", validateAndLock(nil)),
+		namedStep("ship", nil),
+	}, WithAutoAdvance(), WithValue(keyOrderID, "A-17"), WithObserver(log))
+	result := observeRun(t, m)
+
+	if result.Outcome != OutcomeSucceeded {
+		t.Fatalf("Expected success, got %s (%v)", result.Outcome, result.Err)
+	}
+	nested := Result{Path: result.Path[1].Nested}
+	if got := nested.StepNames(); !reflect.DeepEqual(got, []string{"validate", "lock"}) {
+		t.Errorf("Expected nested path [validate lock], got %v", got)
+	}
+	if total, _ := keyTotal.Output(result); total != 4 {
+		t.Errorf("Expected shared data written by the sub-mission, got %v", total)
+	}
+
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	var forwarded []string
+	for _, e := range log.events {
+		if len(e.Path) == 1 && e.Path[0] == "validate-and-lock" && e.Kind == EventStepFinished {
+			forwarded = append(forwarded, e.Step)
+		}
+	}
+	if !reflect.DeepEqual(forwarded, []string{"validate", "lock"}) {
+		t.Errorf("Expected nested events with their path, got %v", forwarded)
+	}
+}
+
+func TestMission_SubMissionScopedData(t *testing.T) {
+	m := mustNew(t, []StepDisposer{
+		SubMission("validate-and-lock", validateAndLock(nil),
+			WithScopedData([]string{keyOrderID.Name()}, []string{keyTotal.Name()})),
+	}, WithAutoAdvance(), WithValue(keyOrderID, "B-2"))
+	m.Start()
+	result := waitResult(t, m)
+
+	if total, _ := keyTotal.Output(result); total != 3 {
+		t.Errorf("Expected output copied back, got %v", total)
+	}
+	if _, ok := keyCount.Output(result); ok {
+		t.Error("Values not listed as outputs should stay in the sub-mission")
+	}
+}
+
+func TestMission_SubMissionFailure(t *testing.T) {
+	errLocked := errors.New("already locked")
+	m := mustNew(t, []StepDisposer{
+		SubMission("validate-and-lock", validateAndLock(errLocked)),
+	}, WithAutoAdvance())
+	m.Start()
+	result := waitResult(t, m)
+
+	if result.Outcome != OutcomeFailed || !errors.Is(result.Err, errLocked) {
+		t.Fatalf("Expected sub-mission failure to fail the parent, got %s (%v)", result.Outcome, result.Err)
+	}
+	if nested := result.Path[0].Nested; len(nested) != 2 || nested[1].Err == nil {
+		t.Errorf("Expected failed nested record, got %+v", nested)
+	}
+}
+
+func TestMission_SubMissionCancel(t *testing.T) {
+	cancelled := make(chan struct{})
+	m := mustNew(t, []StepDisposer{
+		SubMission("wait", func() (*Mission, error) {
+			return New([]StepDisposer{&ctxFuncStep{name: "block", fn: func(ctx context.Context) error {
+				<-ctx.Done()
+				close(cancelled)
+				return ctx.Err()
+			}}})
+		}),
+	})
+	m.Start()
+	time.Sleep(10 * time.Millisecond)
+	m.Stop()
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("Stopping the parent should cancel the sub-mission")
+	}
+	<-m.Done()
+	if outcome := m.Result().Outcome; outcome != OutcomeStopped {
+		t.Errorf("Expected parent to be stopped, got %s", outcome)
+	}
+}
@@ -0,0 +1,235 @@
+package mission
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"lbox/pkg/clock"
+)
+
+// WithStepTimeout limits each attempt of the named step to d. When it expires
+// the step context is cancelled and the attempt fails with ErrStepTimeout,
+// which the error policy of the step handles like any other failure. An
+// attempt ignoring its context keeps running in the background, possibly
+// alongside the next attempt, and its navigation requests are rejected with
+// ErrAttemptAbandoned
+func WithStepTimeout(stepName string, d time.Duration) Option {
+	return func(m *Mission) {
+		if m.stepTimeouts == nil {
+			m.stepTimeouts = make(map[string]time.Duration)
+		}
+		m.stepTimeouts[stepName] = d
+	}
+}
+
+// WithDefaultStepTimeout limits the attempts of steps without their own
+// timeout, see WithStepTimeout
+func WithDefaultStepTimeout(d time.Duration) Option {
+	return func(m *Mission) {
+		m.defaultStepTimeout = d
+	}
+}
+
+// WithTimeout bounds every run of the mission to d. Steps running when the
+// deadline passes fail with ErrStepTimeout and so does the mission: Retry and
+// Skip policies are not applied, and JumpTo only with WithDeadlineGrace. Retry
+// backoff is bounded by the deadline as well. When it passes while no step
+// runs, the mission stops with context.DeadlineExceeded
+func WithTimeout(d time.Duration) Option {
+	return func(m *Mission) {
+		m.timeout = d
+	}
+}
+
+// WithDeadlineGrace lets the JumpTo policy of a step failed by the deadline of
+// WithTimeout run its target. The deadline is extended once by d for the
+// handler and the steps following it
+func WithDeadlineGrace(d time.Duration) Option {
+	return func(m *Mission) {
+		m.deadlineGrace = d
+	}
+}
+
+// WithClock measures step timeouts, the deadline of WithTimeout, retry backoff,
+// signal wait timeouts and the times of records and events on c instead of the
+// system clock, so tests can drive them with a clock.Fake
+func WithClock(c clock.TimerClock) Option {
+	return func(m *Mission) {
+		if c != nil {
+			m.clock = c
+		}
+	}
+}
+
+// validateTimeouts rejects timeouts of unknown steps and non-positive values
+func (m *Mission) validateTimeouts(known map[string]bool) error {
+	for name, d := range m.stepTimeouts {
+		if !known[name] {
+			return fmt.Errorf("timeout for unknown step: %s", name)
+		}
+		if d <= 0 {
+			return fmt.Errorf("timeout of step %s must be positive", name)
+		}
+	}
+	if m.defaultStepTimeout < 0 || m.timeout < 0 || m.deadlineGrace < 0 {
+		return fmt.Errorf("timeouts must not be negative")
+	}
+	return nil
+}
+
+// timeoutFor returns the attempt timeout of the named step, zero for none
+func (m *Mission) timeoutFor(stepName string) time.Duration {
+	if d, ok := m.stepTimeouts[stepName]; ok {
+		return d
+	}
+	return m.defaultStepTimeout
+}
+
+// errDeadline is the cause of a step context cancelled by the mission deadline
+var errDeadline = fmt.Errorf("%w: mission deadline passed", ErrStepTimeout)
+
+// deadlineTimer returns a channel receiving once the deadline of the run
+// passes, nil without a deadline, and a function releasing the timer
+func (m *Mission) deadlineTimer() (<-chan time.Time, func()) {
+	m.mu.Lock()
+	deadline := m.deadline
+	m.mu.Unlock()
+	if deadline.IsZero() {
+		return nil, func() {}
+	}
+	timer := m.clock.NewTimer(deadline.Sub(m.clock.Now()))
+	return timer.C(), func() { timer.Stop() }
+}
+
+// expire stops the mission when its deadline passed while no step was running
+// and reports whether the run is over. It does not stop a mission whose
+// deadline was extended by the grace period meanwhile
+func (m *Mission) expire() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.running {
+		return true
+	}
+	if m.deadline.IsZero() || m.clock.Now().Before(m.deadline) {
+		return false
+	}
+	m.finish(OutcomeStopped, context.DeadlineExceeded)
+	return true
+}
+
+// extendDeadline grants the grace period to a step failed by the deadline and
+// reports whether policy may handle the failure. Only JumpTo is granted it,
+// once per run
+func (m *Mission) extendDeadline(policy ErrorPolicy) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if policy.Action != ActionJump || m.deadlineGrace <= 0 || m.graced {
+		return false
+	}
+	m.graced = true
+	m.deadline = m.deadline.Add(m.deadlineGrace)
+	return true
+}
+
+// backoffContext returns ctx bounded by the deadline of the run, so retries do
+// not wait past it
+func (m *Mission) backoffContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	m.mu.Lock()
+	deadline := m.deadline
+	m.mu.Unlock()
+	if deadline.IsZero() {
+		return ctx, func() {}
+	}
+	return m.withDeadline(ctx, deadline, errDeadline)
+}
+
+// withDeadline returns ctx cancelled with cause once the mission clock
+// reaches deadline. On a clock other than clock.Real the context reports
+// context.Canceled, its cause tells the deadline apart
+func (m *Mission) withDeadline(ctx context.Context, deadline time.Time, cause error) (context.Context, context.CancelFunc) {
+	if _, ok := m.clock.(clock.Real); ok {
+		return context.WithDeadlineCause(ctx, deadline, cause)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	timer := m.clock.NewTimer(deadline.Sub(m.clock.Now()))
+	go func() {
+		defer timer.Stop()
+		select {
+		case <-timer.C():
+			cancel(cause)
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() { cancel(context.Canceled) }
+}
+
+// attemptScope tells whether the mission stopped waiting for a step attempt
+type attemptScope struct {
+	abandoned bool
+}
+
+// disposeWithin runs one attempt of step. With a step timeout or a mission
+// deadline the step runs on its own goroutine, so a step ignoring its context
+// is abandoned instead of blocking the mission. It keeps running in the
+// background until it returns, but its navigation requests are rejected with
+// ErrAttemptAbandoned
+func (m *Mission) disposeWithin(ctx context.Context, step StepDisposer) error {
+	timeout := m.timeoutFor(step.StepName())
+	m.mu.Lock()
+	deadline := m.deadline
+	m.mu.Unlock()
+	if timeout <= 0 && deadline.IsZero() {
+		return m.dispose(ctx, step)
+	}
+
+	stepCtx := ctx
+	if !deadline.IsZero() {
+		var cancel context.CancelFunc
+		stepCtx, cancel = m.withDeadline(stepCtx, deadline, errDeadline)
+		defer cancel()
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		stepCtx, cancel = m.withDeadline(stepCtx, m.clock.Now().Add(timeout), ErrStepTimeout)
+		defer cancel()
+	}
+
+	// The step receives its own handle on the mission to be recognized once
+	// abandoned
+	scope := &attemptScope{}
+	handle := &Mission{missionState: m.missionState, scope: scope}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- handle.dispose(stepCtx, step)
+	}()
+
+	var err error
+	select {
+	case err = <-errc:
+		if err == nil || ctx.Err() != nil || stepCtx.Err() == nil {
+			return err
+		}
+	case <-stepCtx.Done():
+		m.mu.Lock()
+		scope.abandoned = true
+		m.mu.Unlock()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	return m.timedOut(context.Cause(stepCtx), timeout, err)
+}
+
+// timedOut returns the error of an attempt cancelled by cause
+func (m *Mission) timedOut(cause error, timeout time.Duration, err error) error {
+	timeoutErr := errDeadline
+	if cause != errDeadline {
+		timeoutErr = fmt.Errorf("%w after %s", ErrStepTimeout, timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", timeoutErr, err)
+	}
+	return timeoutErr
+}
@@ -0,0 +1,284 @@
+package mission
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"lbox/pkg/clock"
+)
+
+// hungStep ignores its context and blocks until release is closed
+func hungStep(name string, release chan struct{}) StepDisposer {
+	return &FuncStep{name: name, fn: func(*Mission) error {
+		<-release
+		return nil
+	}}
+}
+
+func TestMission_StepTimeoutRetries(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	fake := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	m := mustNew(t, []StepDisposer{hungStep("call", release)},
+		WithClock(fake),
+		WithStepTimeout("call", time.Minute),
+		WithStepPolicy("call", Retry(2, ConstantBackoff(time.Second))),
+	)
+	m.Start()
+
+	// Timeout of the first attempt, backoff, timeout of the second attempt
+	for _, d := range []time.Duration{time.Minute, time.Second, time.Minute} {
+		fake.BlockUntil(1)
+		fake.Advance(d)
+	}
+	result := waitResult(t, m)
+
+	if result.Outcome != OutcomeFailed || !errors.Is(result.Err, ErrStepTimeout) {
+		t.Fatalf("Expected timeout failure, got %s (%v)", result.Outcome, result.Err)
+	}
+	if record := result.Path[0]; !record.TimedOut() || record.Attempts != 2 {
+		t.Errorf("Expected 2 timed out attempts, got %+v", record)
+	}
+}
+
+func TestMission_StepTimeoutPolicy(t *testing.T) {
+	cancelled := make(chan error, 1)
+	steps := []StepDisposer{
+		&ctxFuncStep{name: "slow", fn: func(ctx context.Context) error {
+			<-ctx.Done()
+			cancelled <- ctx.Err()
+			return ctx.Err()
+		}},
+		namedStep("fallback", func(m *Mission) { m.GoNext() }),
+	}
+
+	m := mustNew(t, steps,
+		WithDefaultStepTimeout(20*time.Millisecond),
+		WithStepPolicy("slow", Skip()),
+	)
+	m.Start()
+	result := waitResult(t, m)
+
+	if result.Outcome != OutcomeSucceeded {
+		t.Fatalf("Expected skipped timeout to continue, got %s (%v)", result.Outcome, result.Err)
+	}
+	if len(result.StepErrors) != 1 || !errors.Is(result.StepErrors[0], ErrStepTimeout) {
+		t.Errorf("Expected recorded timeout, got %v", result.StepErrors)
+	}
+	if err := <-cancelled; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected step context to be cancelled by its deadline, got %v", err)
+	}
+}
+
+func TestMission_Deadline(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	fake := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	m := mustNew(t, []StepDisposer{hungStep("hung", release)}, WithClock(fake), WithTimeout(time.Hour))
+	m.Start()
+
+	// Navigation does not wait for the hung step
+	m.GoNext()
+	// The deadline of the run and of the running step
+	fake.BlockUntil(2)
+	fake.Advance(time.Hour)
+	result := waitResult(t, m)
+
+	if result.Outcome != OutcomeFailed || !errors.Is(result.Err, ErrStepTimeout) {
+		t.Errorf("Expected the deadline to fail the running step, got %s (%v)", result.Outcome, result.Err)
+	}
+	if len(result.Path) != 1 || !result.Path[0].TimedOut() {
+		t.Errorf("Expected the step to be marked as timed out, got %+v", result.Path)
+	}
+}
+
+func TestMission_DeadlinePolicy(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	steps := []StepDisposer{
+		hungStep("hung", release),
+		namedStep("next", func(m *Mission) { m.GoNext() }),
+		namedStep("fallback", func(m *Mission) { m.GoNext() }),
+	}
+	m := mustNew(t, steps,
+		WithTimeout(30*time.Millisecond),
+		WithDeadlineGrace(time.Second),
+		WithStepPolicy("hung", JumpTo("fallback")),
+	)
+	m.Start()
+	result := waitResult(t, m)
+
+	if result.Outcome != OutcomeSucceeded {
+		t.Fatalf("Expected the JumpTo policy to handle the deadline, got %s (%v)", result.Outcome, result.Err)
+	}
+	if got := result.StepNames(); !reflect.DeepEqual(got, []string{"hung", "fallback"}) {
+		t.Errorf("Expected the fallback to run, got %v", got)
+	}
+	if len(result.StepErrors) != 1 || !errors.Is(result.StepErrors[0], ErrStepTimeout) || !result.Path[0].TimedOut() {
+		t.Errorf("Expected the timed out step to be recorded, got %v", result.StepErrors)
+	}
+}
+
+// slowStep succeeds after d unless its context is done first
+func slowStep(name string, d time.Duration) StepDisposer {
+	return &ctxFuncStep{name: name, fn: func(ctx context.Context) error {
+		select {
+		case <-time.After(d):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}}
+}
+
+func TestMission_DeadlineIsTerminal(t *testing.T) {
+	failing := 0
+	tests := []struct {
+		name   string
+		first  StepDisposer
+		policy ErrorPolicy
+	}{
+		{name: "skip", first: slowStep("a", 200*time.Millisecond), policy: Skip()},
+		{name: "retry", first: slowStep("a", 200*time.Millisecond), policy: Retry(3, nil)},
+		{name: "jump without grace", first: slowStep("a", 200*time.Millisecond), policy: JumpTo("c")},
+		{name: "retry backoff", first: &FuncStep{name: "a", fn: func(*Mission) error {
+			failing++
+			return errors.New("boom")
+		}}, policy: Retry(3, ConstantBackoff(time.Hour))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps := []StepDisposer{tt.first, slowStep("b", 100*time.Millisecond), slowStep("c", 100*time.Millisecond)}
+			m := mustNew(t, steps, WithTimeout(30*time.Millisecond), WithErrorPolicy(tt.policy), WithAutoAdvance())
+			started := time.Now()
+			m.Start()
+			result := waitResult(t, m)
+
+			if result.Outcome != OutcomeFailed || !errors.Is(result.Err, ErrStepTimeout) {
+				t.Errorf("Expected the deadline to fail the mission, got %s (%v)", result.Outcome, result.Err)
+			}
+			if got := result.StepNames(); !reflect.DeepEqual(got, []string{"a"}) {
+				t.Errorf("Expected no step to run after the deadline, got %v", got)
+			}
+			if elapsed := time.Since(started); elapsed > 150*time.Millisecond {
+				t.Errorf("Expected the run to end at its deadline, took %v", elapsed)
+			}
+		})
+	}
+	if failing != 1 {
+		t.Errorf("Expected the backoff to end at the deadline after 1 attempt, got %d", failing)
+	}
+}
+
+func TestMission_DeadlineGraceBoundsHandler(t *testing.T) {
+	steps := []StepDisposer{
+		slowStep("a", time.Second),
+		slowStep("b", 10*time.Millisecond),
+		slowStep("fallback", time.Second),
+	}
+	m := mustNew(t, steps,
+		WithTimeout(20*time.Millisecond),
+		WithDeadlineGrace(20*time.Millisecond),
+		WithStepPolicy("a", JumpTo("fallback")),
+		WithErrorPolicy(Skip()),
+		WithAutoAdvance(),
+	)
+	m.Start()
+	result := waitResult(t, m)
+
+	if result.Outcome != OutcomeFailed || !errors.Is(result.Err, ErrStepTimeout) {
+		t.Errorf("Expected the handler to fail at the end of the grace, got %s (%v)", result.Outcome, result.Err)
+	}
+	if got := result.StepNames(); !reflect.DeepEqual(got, []string{"a", "fallback"}) {
+		t.Errorf("Expected only the handler to run after the deadline, got %v", got)
+	}
+}
+
+func TestMission_DeadlineBetweenSteps(t *testing.T) {
+	m := mustNew(t, []StepDisposer{NewMockStep("a"), NewMockStep("b")}, WithTimeout(20*time.Millisecond))
+	m.Start()
+
+	// The mission waits for GoNext when the deadline passes
+	select {
+	case <-m.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Mission did not complete in time")
+	}
+	result := m.Result()
+	if result.Outcome != OutcomeStopped || !errors.Is(result.Err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline to stop the idle mission, got %s (%v)", result.Outcome, result.Err)
+	}
+}
+
+func TestNew_TimeoutValidation(t *testing.T) {
+	steps := []StepDisposer{NewMockStep("a")}
+	for name, opt := range map[string]Option{
+		"unknown step":      WithStepTimeout("b", time.Second),
+		"zero step timeout": WithStepTimeout("a", 0),
+		"negative default":  WithDefaultStepTimeout(-time.Second),
+		"negative deadline": WithTimeout(-time.Second),
+	} {
+		if _, err := New(steps, opt); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
+// ctxFuncStep runs fn with the step context
+type ctxFuncStep struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+func (s *ctxFuncStep) StepName() string {
+	return s.name
+}
+
+func (s *ctxFuncStep) Dispose(m *Mission, tags []interface{}) error {
+	return s.DisposeContext(context.Background(), m, tags)
+}
+
+func (s *ctxFuncStep) DisposeContext(ctx context.Context, _ *Mission, _ []interface{}) error {
+	return s.fn(ctx)
+}
+
+func TestMission_AbandonedAttemptNavigation(t *testing.T) {
+	release := make(chan struct{})
+	navigated := make(chan error, 1)
+	entered := make(chan struct{}, 1)
+	steps := []StepDisposer{
+		&FuncStep{name: "slow", fn: func(m *Mission) error {
+			<-release
+			navigated <- m.GoNext()
+			return nil
+		}},
+		namedStep("b", func(*Mission) { entered <- struct{}{} }),
+	}
+
+	m := mustNew(t, steps,
+		WithStepTimeout("slow", 20*time.Millisecond),
+		WithStepPolicy("slow", Skip()),
+	)
+	m.Start()
+	<-entered
+
+	close(release)
+	if err := <-navigated; !errors.Is(err, ErrAttemptAbandoned) {
+		t.Errorf("Expected the abandoned attempt to be rejected, got %v", err)
+	}
+	if history := m.History(); !reflect.DeepEqual(history, []string{"slow", "b"}) || !m.IsRunning() {
+		t.Errorf("Expected the mission to stay on b, got %v", history)
+	}
+
+	m.GoNext()
+	if result := waitResult(t, m); result.Outcome != OutcomeSucceeded {
+		t.Errorf("Expected success, got %s (%v)", result.Outcome, result.Err)
+	}
+}
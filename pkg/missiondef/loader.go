@@ -0,0 +1,566 @@
+package missiondef
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"lbox/pkg/mission"
+)
+
+// Error is a definition error pointing at the offending line
+type Error struct {
+	File string
+	Line int
+	// Mission is the name of the definition, when it declares one
+	Mission string
+	Msg     string
+}
+
+func (e *Error) Error() string {
+	pos := fmt.Sprintf("line %d", e.Line)
+	if e.File != "" {
+		pos = fmt.Sprintf("%s:%d", e.File, e.Line)
+	}
+	if e.Mission != "" {
+		pos = fmt.Sprintf("mission %s, %s", e.Mission, pos)
+	}
+	return pos + ": " + e.Msg
+}
+
+// errorf creates an Error at the line of node
+func errorf(node *yaml.Node, format string, args ...interface{}) *Error {
+	return &Error{Line: node.Line, Msg: fmt.Sprintf(format, args...)}
+}
+
+var yamlLine = regexp.MustCompile(`line (\d+): `)
+
+// wrapYAMLError turns a decoding error into an Error, using line when the
+// error does not name one
+func wrapYAMLError(err error, line int) error {
+	var defErr *Error
+	if errors.As(err, &defErr) {
+		return defErr
+	}
+
+	msg := err.Error()
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) && len(typeErr.Errors) > 0 {
+		msg = typeErr.Errors[0]
+	}
+	msg = strings.TrimPrefix(msg, "yaml: ")
+	if match := yamlLine.FindStringSubmatchIndex(msg); match != nil {
+		line, _ = strconv.Atoi(msg[match[2]:match[3]])
+		msg = msg[:match[0]] + msg[match[1]:]
+	}
+	return &Error{Line: line, Msg: msg}
+}
+
+// Load builds a mission from a YAML or JSON definition, creating its steps
+// with the factories of registry. opts are applied after the options of the
+// definition
+func Load(data []byte, registry *Registry, opts ...mission.Option) (*mission.Mission, error) {
+	var def definition
+	if err := yaml.Unmarshal(data, &def); err != nil {
+		return nil, wrapYAMLError(err, 1)
+	}
+	m, err := def.build(registry, opts)
+	var defErr *Error
+	if errors.As(err, &defErr) {
+		defErr.Mission = def.Name
+	}
+	return m, err
+}
+
+// LoadFile builds a mission from the definition stored at path, see Load
+func LoadFile(path string, registry *Registry, opts ...mission.Option) (*mission.Mission, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := Load(data, registry, opts...)
+	var defErr *Error
+	if errors.As(err, &defErr) {
+		defErr.File = path
+	}
+	return m, err
+}
+
+// definition is the document describing a mission
+type definition struct {
+	Name            string                 `yaml:"name"`
+	AutoAdvance     bool                   `yaml:"autoAdvance"`
+	Timeout         duration               `yaml:"timeout"`
+	StepTimeout     duration               `yaml:"stepTimeout"`
+	MaxParallel     int                    `yaml:"maxParallel"`
+	ContinueOnError bool                   `yaml:"continueOnError"`
+	Rollback        bool                   `yaml:"rollback"`
+	OnError         *policyDef             `yaml:"onError"`
+	Inputs          map[string]interface{} `yaml:"inputs"`
+	Steps           []stepDef              `yaml:"steps"`
+	Branches        []branchDef            `yaml:"branches"`
+	Loops           []loopDef              `yaml:"loops"`
+	node            *yaml.Node
+}
+
+func (d *definition) UnmarshalYAML(node *yaml.Node) error {
+	type plain definition
+	if err := decodeStrict(node, (*plain)(d)); err != nil {
+		return err
+	}
+	d.node = node
+	return nil
+}
+
+// stepDef describes one step
+type stepDef struct {
+	Name      string     `yaml:"name"`
+	Type      string     `yaml:"type"`
+	Params    yaml.Node  `yaml:"params"`
+	Timeout   duration   `yaml:"timeout"`
+	OnError   *policyDef `yaml:"onError"`
+	DependsOn []string   `yaml:"dependsOn"`
+	node      *yaml.Node
+}
+
+func (s *stepDef) UnmarshalYAML(node *yaml.Node) error {
+	type plain stepDef
+	if err := decodeStrict(node, (*plain)(s)); err != nil {
+		return err
+	}
+	s.node = node
+	return nil
+}
+
+// policyDef describes an error policy
+type policyDef struct {
+	Action      string   `yaml:"action"`
+	MaxAttempts int      `yaml:"maxAttempts"`
+	Backoff     string   `yaml:"backoff"`
+	Delay       duration `yaml:"delay"`
+	MaxDelay    duration `yaml:"maxDelay"`
+	Target      string   `yaml:"target"`
+	node        *yaml.Node
+}
+
+func (p *policyDef) UnmarshalYAML(node *yaml.Node) error {
+	type plain policyDef
+	if err := decodeStrict(node, (*plain)(p)); err != nil {
+		return err
+	}
+	p.node = node
+	return nil
+}
+
+// conditionDef tests a value of the mission data. Without equals it holds when
+// the value is present
+type conditionDef struct {
+	Key    string    `yaml:"key"`
+	Equals yaml.Node `yaml:"equals"`
+	node   *yaml.Node
+}
+
+func (c *conditionDef) UnmarshalYAML(node *yaml.Node) error {
+	type plain conditionDef
+	if err := decodeStrict(node, (*plain)(c)); err != nil {
+		return err
+	}
+	c.node = node
+	return nil
+}
+
+// branchDef describes a conditional transition
+type branchDef struct {
+	From string       `yaml:"from"`
+	To   string       `yaml:"to"`
+	When conditionDef `yaml:"when"`
+	node *yaml.Node
+}
+
+func (b *branchDef) UnmarshalYAML(node *yaml.Node) error {
+	type plain branchDef
+	if err := decodeStrict(node, (*plain)(b)); err != nil {
+		return err
+	}
+	b.node = node
+	return nil
+}
+
+// loopDef describes a bounded loop
+type loopDef struct {
+	First string        `yaml:"first"`
+	Last  string        `yaml:"last"`
+	Max   int           `yaml:"max"`
+	Until *conditionDef `yaml:"until"`
+	node  *yaml.Node
+}
+
+func (l *loopDef) UnmarshalYAML(node *yaml.Node) error {
+	type plain loopDef
+	if err := decodeStrict(node, (*plain)(l)); err != nil {
+		return err
+	}
+	l.node = node
+	return nil
+}
+
+// duration is a time.Duration written like "1m30s"
+type duration time.Duration
+
+func (d *duration) UnmarshalYAML(node *yaml.Node) error {
+	var text string
+	if err := node.Decode(&text); err != nil {
+		return wrapYAMLError(err, node.Line)
+	}
+	parsed, err := time.ParseDuration(text)
+	if err != nil || parsed < 0 {
+		return errorf(node, "invalid duration %q", text)
+	}
+	*d = duration(parsed)
+	return nil
+}
+
+// decodeStrict decodes a mapping node into v, rejecting keys v does not
+// declare so that typos are reported at their line
+func decodeStrict(node *yaml.Node, v interface{}) error {
+	if node.Kind != yaml.MappingNode {
+		return errorf(node, "expected a mapping")
+	}
+	known := yamlKeys(v)
+	for i := 0; i < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if !known[key.Value] {
+			return errorf(key, "unknown field %q", key.Value)
+		}
+	}
+	if err := node.Decode(v); err != nil {
+		return wrapYAMLError(err, node.Line)
+	}
+	return nil
+}
+
+// yamlKeys returns the yaml keys of the struct v points to
+func yamlKeys(v interface{}) map[string]bool {
+	t := reflect.TypeOf(v).Elem()
+	keys := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ","); name != "" {
+			keys[name] = true
+		}
+	}
+	return keys
+}
+
+// build validates the definition and creates the mission
+func (d *definition) build(registry *Registry, extra []mission.Option) (*mission.Mission, error) {
+	if d.node == nil || len(d.Steps) == 0 {
+		line := 1
+		if d.node != nil {
+			line = d.node.Line
+		}
+		return nil, &Error{Line: line, Msg: "mission has no steps"}
+	}
+
+	names := make(map[string]bool, len(d.Steps))
+	for _, s := range d.Steps {
+		switch {
+		case s.Name == "":
+			return nil, errorf(s.node, "step without name")
+		case names[s.Name]:
+			return nil, errorf(s.node, "duplicate step name: %s", s.Name)
+		}
+		names[s.Name] = true
+	}
+
+	var opts []mission.Option
+	if d.OnError != nil {
+		policy, err := d.OnError.build(names)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, mission.WithErrorPolicy(policy))
+	}
+
+	steps := make([]mission.StepDisposer, 0, len(d.Steps))
+	for _, s := range d.Steps {
+		step, stepOpts, err := s.build(registry, names)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+		opts = append(opts, stepOpts...)
+	}
+
+	if err := d.validateGraph(); err != nil {
+		return nil, err
+	}
+
+	for _, b := range d.Branches {
+		for _, name := range []string{b.From, b.To} {
+			if !names[name] {
+				return nil, errorf(b.node, "branch refers to unknown step: %q", name)
+			}
+		}
+		when, err := b.When.build(b.node)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, mission.WithBranch(b.From, when, b.To))
+	}
+	for _, l := range d.Loops {
+		for _, name := range []string{l.First, l.Last} {
+			if !names[name] {
+				return nil, errorf(l.node, "loop refers to unknown step: %q", name)
+			}
+		}
+		if d.indexOf(l.First) > d.indexOf(l.Last) {
+			return nil, errorf(l.node, "loop %s..%s starts after it ends", l.First, l.Last)
+		}
+		if l.Max < 1 {
+			return nil, errorf(valueNode(l.node, "max"), "loop %s..%s needs a max of at least 1", l.First, l.Last)
+		}
+		until := mission.Condition(func(*mission.Data) bool { return false })
+		if l.Until != nil {
+			var err error
+			if until, err = l.Until.build(l.node); err != nil {
+				return nil, err
+			}
+		}
+		opts = append(opts, mission.WithLoop(l.First, l.Last, until, l.Max))
+	}
+
+	if d.AutoAdvance {
+		opts = append(opts, mission.WithAutoAdvance())
+	}
+	if d.Timeout > 0 {
+		opts = append(opts, mission.WithTimeout(time.Duration(d.Timeout)))
+	}
+	if d.StepTimeout > 0 {
+		opts = append(opts, mission.WithDefaultStepTimeout(time.Duration(d.StepTimeout)))
+	}
+	if d.MaxParallel > 0 {
+		opts = append(opts, mission.WithMaxParallel(d.MaxParallel))
+	}
+	if d.ContinueOnError {
+		opts = append(opts, mission.WithContinueOnError())
+	}
+	if d.Rollback {
+		opts = append(opts, mission.WithRollback())
+	}
+	if len(d.Inputs) > 0 {
+		opts = append(opts, mission.WithInputs(d.Inputs))
+	}
+
+	// Errors left here come from extra or lack a more precise line
+	m, err := mission.New(steps, append(opts, extra...)...)
+	if err != nil {
+		return nil, &Error{Line: d.node.Line, Msg: err.Error()}
+	}
+	return m, nil
+}
+
+// indexOf returns the position of the named step, or -1
+func (d *definition) indexOf(name string) int {
+	for i, s := range d.Steps {
+		if s.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// validateGraph checks the dependencies of a DAG definition: they must not
+// form a cycle, and branches, loops and jump policies are not supported
+func (d *definition) validateGraph() error {
+	dag := false
+	for _, s := range d.Steps {
+		dag = dag || len(s.DependsOn) > 0
+	}
+	if !dag {
+		return nil
+	}
+
+	if len(d.Branches) > 0 {
+		return errorf(d.Branches[0].node, "branches are not supported with dependsOn")
+	}
+	if len(d.Loops) > 0 {
+		return errorf(d.Loops[0].node, "loops are not supported with dependsOn")
+	}
+	if d.OnError != nil && d.OnError.Action == "jump" {
+		return errorf(d.OnError.node, "jumps are not supported with dependsOn")
+	}
+	for _, s := range d.Steps {
+		if s.OnError != nil && s.OnError.Action == "jump" {
+			return errorf(s.OnError.node, "jumps are not supported with dependsOn")
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(d.Steps))
+	var stack []string
+	var visit func(i int) error
+	visit = func(i int) error {
+		s := d.Steps[i]
+		state[s.Name] = visiting
+		stack = append(stack, s.Name)
+		for _, dep := range s.DependsOn {
+			switch state[dep] {
+			case visiting:
+				for j, name := range stack {
+					if name == dep {
+						cycle := append(append([]string(nil), stack[j:]...), dep)
+						return errorf(d.Steps[d.indexOf(dep)].node, "dependency cycle: %s", strings.Join(cycle, " -> "))
+					}
+				}
+			case unvisited:
+				if err := visit(d.indexOf(dep)); err != nil {
+					return err
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[s.Name] = visited
+		return nil
+	}
+	for i, s := range d.Steps {
+		if state[s.Name] == unvisited {
+			if err := visit(i); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// valueNode returns the value of key in the mapping node, or node itself when
+// the key is absent
+func valueNode(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return node
+}
+
+// build creates the step with its factory and returns the options it needs
+func (s *stepDef) build(registry *Registry, names map[string]bool) (mission.StepDisposer, []mission.Option, error) {
+	if s.Type == "" {
+		return nil, nil, errorf(s.node, "step %s has no type", s.Name)
+	}
+	factory, ok := registry.lookup(s.Type)
+	if !ok {
+		return nil, nil, errorf(s.node, "unknown step type %q, registered: %s", s.Type, strings.Join(registry.Types(), ", "))
+	}
+
+	params := Params{node: &s.Params}
+	step, err := factory(s.Name, params)
+	if err != nil {
+		var defErr *Error
+		if errors.As(err, &defErr) {
+			return nil, nil, err
+		}
+		return nil, nil, errorf(s.node, "step %s: %v", s.Name, err)
+	}
+	if step == nil || step.StepName() != s.Name {
+		return nil, nil, errorf(s.node, "factory of type %s must return a step named %s", s.Type, s.Name)
+	}
+
+	var opts []mission.Option
+	for _, dep := range s.DependsOn {
+		if !names[dep] {
+			return nil, nil, errorf(s.node, "step %s depends on unknown step: %s", s.Name, dep)
+		}
+	}
+	if len(s.DependsOn) > 0 {
+		opts = append(opts, mission.WithDependencies(s.Name, s.DependsOn...))
+	}
+	if s.Timeout > 0 {
+		opts = append(opts, mission.WithStepTimeout(s.Name, time.Duration(s.Timeout)))
+	}
+	if s.OnError != nil {
+		policy, err := s.OnError.build(names)
+		if err != nil {
+			return nil, nil, err
+		}
+		opts = append(opts, mission.WithStepPolicy(s.Name, policy))
+	}
+	return step, opts, nil
+}
+
+// build converts the definition into a mission.ErrorPolicy
+func (p *policyDef) build(names map[string]bool) (mission.ErrorPolicy, error) {
+	switch p.Action {
+	case "fail", "":
+		return mission.Fail(), nil
+	case "skip":
+		return mission.Skip(), nil
+	case "jump":
+		if !names[p.Target] {
+			return mission.ErrorPolicy{}, errorf(p.node, "jump target not found: %q", p.Target)
+		}
+		return mission.JumpTo(p.Target), nil
+	case "retry":
+		if p.MaxAttempts < 1 {
+			return mission.ErrorPolicy{}, errorf(p.node, "retry needs maxAttempts of at least 1")
+		}
+		var backoff mission.Backoff
+		switch p.Backoff {
+		case "", "constant":
+			backoff = mission.ConstantBackoff(time.Duration(p.Delay))
+		case "exponential":
+			max := time.Duration(p.MaxDelay)
+			// Without maxDelay the wait stops growing after ten doublings
+			if max <= 0 {
+				max = time.Duration(p.Delay) << 10
+			}
+			backoff = mission.ExponentialBackoff(time.Duration(p.Delay), max)
+		default:
+			return mission.ErrorPolicy{}, errorf(p.node, "unknown backoff %q, expected constant or exponential", p.Backoff)
+		}
+		return mission.Retry(p.MaxAttempts, backoff), nil
+	default:
+		return mission.ErrorPolicy{}, errorf(p.node, "unknown action %q, expected fail, retry, skip or jump", p.Action)
+	}
+}
+
+// build converts the definition into a mission.Condition, reporting a
+// missing condition at the line of owner
+func (c *conditionDef) build(owner *yaml.Node) (mission.Condition, error) {
+	if c.node == nil {
+		return nil, errorf(owner, "condition is missing")
+	}
+	if c.Key == "" {
+		return nil, errorf(c.node, "condition needs a key")
+	}
+
+	key := c.Key
+	if c.Equals.Kind == 0 {
+		return func(d *mission.Data) bool {
+			_, ok := d.Get(key)
+			return ok
+		}, nil
+	}
+	var expected interface{}
+	if err := c.Equals.Decode(&expected); err != nil {
+		return nil, wrapYAMLError(err, c.Equals.Line)
+	}
+	// Values are compared in their printed form, so 3 from the definition
+	// matches an int64 or float64 3 in the data
+	want := fmt.Sprint(expected)
+	return func(d *mission.Data) bool {
+		value, ok := d.Get(key)
+		return ok && fmt.Sprint(value) == want
+	}, nil
+}
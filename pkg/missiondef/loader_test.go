@@ -0,0 +1,206 @@
+package missiondef
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"lbox/pkg/mission"
+)
+
+// setStep stores its params into the mission data
+type setStep struct {
+	name   string
+	params struct {
+		Key   string `yaml:"key"`
+		Value string `yaml:"value"`
+		Fail  int    `yaml:"fail"`
+	}
+	runs int
+	mu   sync.Mutex
+}
+
+func (s *setStep) StepName() string {
+	return s.name
+}
+
+func (s *setStep) Dispose(m *mission.Mission, _ []interface{}) error {
+	s.mu.Lock()
+	s.runs++
+	runs := s.runs
+	s.mu.Unlock()
+	if runs <= s.params.Fail {
+		return fmt.Errorf("attempt %d failed", runs)
+	}
+	if s.params.Key != "" {
+		m.Data().Set(s.params.Key, s.params.Value)
+	}
+	return nil
+}
+
+func newTestRegistry(t *testing.T) *Registry {
+	t.Helper()
+	r := NewRegistry()
+	err := r.Register("set", func(name string, params Params) (mission.StepDisposer, error) {
+		s := &setStep{name: name}
+		if err := params.Decode(&s.params); err != nil {
+			return nil, err
+		}
+		return s, nil
+	})
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	err = r.Register("broken", func(string, Params) (mission.StepDisposer, error) {
+		return nil, errors.New("missing credentials")
+	})
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	return r
+}
+
+func run(t *testing.T, m *mission.Mission) mission.Result {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	m.Start()
+	result, _ := m.Wait(ctx)
+	if ctx.Err() != nil {
+		t.Fatal("Mission did not complete in time")
+	}
+	return result
+}
+
+const approvalFlow = `
+name: approval
+autoAdvance: true
+stepTimeout: 1s
+inputs:
+  region: eu
+steps:
+  - name: check
+    type: set
+    params: {key: decision, value: approve}
+    onError: {action: retry, maxAttempts: 3, backoff: exponential, delay: 1ms, maxDelay: 4ms}
+  - name: reject
+    type: set
+    params: {key: outcome, value: rejected}
+  - name: approve
+    type: set
+    timeout: 500ms
+    params:
+      key: outcome
+      value: approved
+      fail: 2
+    onError:
+      action: retry
+      maxAttempts: 3
+branches:
+  - from: check
+    when: {key: decision, equals: approve}
+    to: approve
+`
+
+func TestLoad_YAML(t *testing.T) {
+	m, err := Load([]byte(approvalFlow), newTestRegistry(t))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	result := run(t, m)
+
+	if result.Outcome != mission.OutcomeSucceeded {
+		t.Fatalf("Expected success, got %s (%v)", result.Outcome, result.Err)
+	}
+	if names := result.StepNames(); strings.Join(names, ",") != "check,approve" {
+		t.Errorf("Expected branch to approve, got %v", names)
+	}
+	if result.Path[1].Attempts != 3 {
+		t.Errorf("Expected retry policy with 3 attempts, got %d", result.Path[1].Attempts)
+	}
+	if result.Outputs["outcome"] != "approved" || result.Outputs["region"] != "eu" {
+		t.Errorf("Unexpected outputs %v", result.Outputs)
+	}
+}
+
+func TestLoad_JSON(t *testing.T) {
+	definition := `{
+  "autoAdvance": true,
+  "steps": [
+    {"name": "fetch", "type": "set", "params": {"key": "body", "value": "ok"}},
+    {"name": "store", "type": "set", "dependsOn": ["fetch"]}
+  ]
+}`
+	m, err := Load([]byte(definition), newTestRegistry(t))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if result := run(t, m); result.Outcome != mission.OutcomeSucceeded || len(result.Path) != 2 {
+		t.Errorf("Expected DAG mission to succeed, got %s %v", result.Outcome, result.StepNames())
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		definition string
+		line       int
+		contains   string
+	}{
+		{"syntax", "steps: [\n", 1, "did not find expected"},
+		{"no steps", "name: empty\n", 1, "no steps"},
+		{"unknown field", "steps:\n  - name: a\n    type: set\n    retries: 3\n", 4, `unknown field "retries"`},
+		{"unknown type", "steps:\n  - name: a\n    type: http\n", 2, `unknown step type "http"`},
+		{"duplicate", "steps:\n  - {name: a, type: set}\n  - {name: a, type: set}\n", 3, "duplicate step name"},
+		{"bad duration", "steps:\n  - name: a\n    type: set\n    timeout: soon\n", 4, `invalid duration "soon"`},
+		{"param type", "steps:\n  - name: a\n    type: set\n    params:\n      fail: many\n", 5, "cannot unmarshal"},
+		{"factory error", "steps:\n  - {name: a, type: set}\n  - {name: b, type: broken}\n", 3, "missing credentials"},
+		{"jump target", "steps:\n  - name: a\n    type: set\n    onError:\n      action: jump\n      target: nowhere\n", 5, "jump target not found"},
+		{"action", "onError: {action: panic}\nsteps:\n  - {name: a, type: set}\n", 1, `unknown action "panic"`},
+		{"dependency", "steps:\n  - {name: a, type: set, dependsOn: [z]}\n", 2, "unknown step: z"},
+		{"branch", "steps:\n  - {name: a, type: set}\nbranches:\n  - {from: a, to: b, when: {key: x}}\n", 4, `unknown step: "b"`},
+		{"condition", "steps:\n  - {name: a, type: set}\n  - {name: b, type: set}\nbranches:\n  - {from: a, to: b}\n", 5, "condition is missing"},
+		{"cycle", "steps:\n  - {name: a, type: set}\n  - {name: b, type: set, dependsOn: [a, c]}\n  - {name: c, type: set, dependsOn: [b]}\n", 3, "dependency cycle: b -> c -> b"},
+		{"loop order", "steps:\n  - {name: a, type: set}\n  - {name: b, type: set}\nloops:\n  - {first: b, last: a, max: 2}\n", 5, "starts after it ends"},
+		{"loop max", "steps:\n  - {name: a, type: set}\nloops:\n  - first: a\n    last: a\n    max: 0\n", 6, "max of at least 1"},
+		{"dag loop", "steps:\n  - {name: a, type: set}\n  - {name: b, type: set, dependsOn: [a]}\nloops:\n  - {first: a, last: b, max: 2}\n", 5, "loops are not supported"},
+		{"dag jump", "steps:\n  - {name: a, type: set}\n  - name: b\n    type: set\n    dependsOn: [a]\n    onError: {action: jump, target: a}\n", 6, "jumps are not supported"},
+	}
+	registry := newTestRegistry(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load([]byte(tt.definition), registry)
+			var defErr *Error
+			if !errors.As(err, &defErr) {
+				t.Fatalf("Expected *Error, got %v", err)
+			}
+			if defErr.Line != tt.line || !strings.Contains(defErr.Msg, tt.contains) {
+				t.Errorf("Expected line %d containing %q, got %v", tt.line, tt.contains, err)
+			}
+		})
+	}
+}
+
+func TestLoad_ErrorNamesMission(t *testing.T) {
+	_, err := Load([]byte("name: nightly\nsteps:\n  - {name: a, type: http}\n"), newTestRegistry(t))
+	if err == nil || !strings.HasPrefix(err.Error(), "mission nightly, line 3: ") {
+		t.Errorf("Expected error prefixed with the mission name, got %v", err)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken.yaml")
+	if err := os.WriteFile(path, []byte("steps:\n  - name: a\n    type: http\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := LoadFile(path, newTestRegistry(t))
+	if err == nil || !strings.HasPrefix(err.Error(), path+":2: ") {
+		t.Errorf("Expected error prefixed with %s:2, got %v", path, err)
+	}
+}
@@ -0,0 +1,80 @@
+// Package missiondef builds missions from YAML or JSON definitions. Steps are
+// created by factories registered per step type, so a definition can compose
+// existing step implementations without Go code.
+package missiondef
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"lbox/pkg/mission"
+)
+
+// Factory creates the step called name from the params of its definition
+type Factory func(name string, params Params) (mission.StepDisposer, error)
+
+// Params holds the params block of a step definition
+type Params struct {
+	node *yaml.Node
+}
+
+// Decode stores the params into v, which is usually a struct with yaml tags.
+// Missing params leave v untouched
+func (p Params) Decode(v interface{}) error {
+	if p.node == nil || p.node.Kind == 0 {
+		return nil
+	}
+	if err := p.node.Decode(v); err != nil {
+		return wrapYAMLError(err, p.node.Line)
+	}
+	return nil
+}
+
+// Registry maps step types to their factories
+type Registry struct {
+	factories map[string]Factory
+	mu        sync.RWMutex
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{factories: make(map[string]Factory)}
+}
+
+// Register adds the factory of stepType, rejecting types registered twice
+func (r *Registry) Register(stepType string, factory Factory) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if stepType == "" || factory == nil {
+		return fmt.Errorf("step type and factory are required")
+	}
+	if _, ok := r.factories[stepType]; ok {
+		return fmt.Errorf("step type already registered: %s", stepType)
+	}
+	r.factories[stepType] = factory
+	return nil
+}
+
+// Types returns the registered step types in sorted order
+func (r *Registry) Types() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	types := make([]string, 0, len(r.factories))
+	for stepType := range r.factories {
+		types = append(types, stepType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+func (r *Registry) lookup(stepType string) (Factory, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	factory, ok := r.factories[stepType]
+	return factory, ok
+}
@@ -0,0 +1,39 @@
+package missiondef
+
+import (
+	"reflect"
+	"testing"
+
+	"lbox/pkg/mission"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	factory := func(name string, _ Params) (mission.StepDisposer, error) {
+		return &setStep{name: name}, nil
+	}
+
+	for _, stepType := range []string{"wait", "http"} {
+		if err := r.Register(stepType, factory); err != nil {
+			t.Fatalf("Register %s failed: %v", stepType, err)
+		}
+	}
+	if err := r.Register("http", factory); err == nil {
+		t.Error("Expected duplicate registration to fail")
+	}
+	if err := r.Register("", factory); err == nil {
+		t.Error("Expected empty step type to be rejected")
+	}
+	if types := r.Types(); !reflect.DeepEqual(types, []string{"http", "wait"}) {
+		t.Errorf("Expected sorted types, got %v", types)
+	}
+}
+
+func TestParams_DecodeMissing(t *testing.T) {
+	params := struct {
+		URL string `yaml:"url"`
+	}{URL: "default"}
+	if err := (Params{}).Decode(&params); err != nil || params.URL != "default" {
+		t.Errorf("Missing params should leave the target untouched, got %q (%v)", params.URL, err)
+	}
+}
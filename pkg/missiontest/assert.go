@@ -0,0 +1,72 @@
+package missiontest
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"lbox/pkg/mission"
+)
+
+// Path returns the names of the steps in the path of result
+func Path(result mission.Result) []string {
+	names := make([]string, 0, len(result.Path))
+	for _, record := range result.Path {
+		names = append(names, record.Name)
+	}
+	return names
+}
+
+// AssertPath fails the test unless result executed exactly the named steps,
+// in order
+func AssertPath(t testing.TB, result mission.Result, want ...string) {
+	t.Helper()
+	assertNames(t, "path", Path(result), want)
+}
+
+// AssertOutcome fails the test unless result ended with want
+func AssertOutcome(t testing.TB, result mission.Result, want mission.Outcome) {
+	t.Helper()
+	if result.Outcome != want {
+		t.Errorf("missiontest: expected outcome %v, got %v (err %v)", want, result.Outcome, result.Err)
+	}
+}
+
+// AssertStepFailed fails the test unless the last execution of the named step
+// in result failed with an error matching target. A nil target accepts any
+// error
+func AssertStepFailed(t testing.TB, result mission.Result, step string, target error) {
+	t.Helper()
+	for i := len(result.Path) - 1; i >= 0; i-- {
+		record := result.Path[i]
+		if record.Name != step {
+			continue
+		}
+		if record.Err == nil {
+			t.Errorf("missiontest: expected step %s to fail, it succeeded", step)
+		} else if target != nil && !errors.Is(record.Err, target) {
+			t.Errorf("missiontest: expected step %s to fail with %v, got %v", step, target, record.Err)
+		}
+		return
+	}
+	t.Errorf("missiontest: step %s not in path %v", step, Path(result))
+}
+
+// AssertCalls fails the test unless step was invoked n times
+func AssertCalls(t testing.TB, step *ScriptedStep, n int) {
+	t.Helper()
+	if got := step.CallCount(); got != n {
+		t.Errorf("missiontest: expected step %s to be called %d times, got %d", step.StepName(), n, got)
+	}
+}
+
+// assertNames compares two lists of step names
+func assertNames(t testing.TB, what string, got, want []string) {
+	t.Helper()
+	if len(got) == 0 && len(want) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("missiontest: expected %s %v, got %v", what, want, got)
+	}
+}
@@ -0,0 +1,38 @@
+package missiontest
+
+import (
+	"errors"
+	"testing"
+
+	"lbox/pkg/mission"
+)
+
+func TestAssertions(t *testing.T) {
+	result := mission.Result{
+		Outcome: mission.OutcomeFailed,
+		Path: []mission.StepRecord{
+			{Name: "a"},
+			{Name: "b", Err: errBoom},
+		},
+	}
+
+	pass := &recorder{TB: t}
+	AssertPath(pass, result, "a", "b")
+	AssertOutcome(pass, result, mission.OutcomeFailed)
+	AssertStepFailed(pass, result, "b", errBoom)
+	AssertStepFailed(pass, result, "b", nil)
+	if len(pass.failures) != 0 {
+		t.Errorf("Expected the assertions to pass, got %v", pass.failures)
+	}
+
+	fail := &recorder{TB: t}
+	AssertPath(fail, result, "a")
+	AssertOutcome(fail, result, mission.OutcomeSucceeded)
+	AssertStepFailed(fail, result, "a", nil)
+	AssertStepFailed(fail, result, "b", errors.New("other"))
+	AssertStepFailed(fail, result, "c", nil)
+	AssertCalls(fail, Step("x"), 1)
+	if len(fail.failures) != 6 {
+		t.Errorf("Expected 6 failures, got %v", fail.failures)
+	}
+}
@@ -0,0 +1,245 @@
+// Package missiontest runs missions deterministically in tests. A Harness
+// executes a mission step by step or to completion and records the executed
+// steps, while ScriptedStep plays back scripted successes, errors, panics and
+// delays. Delays, step timeouts, the mission deadline, retry backoff and
+// signal wait timeouts are all measured on the fake clock of the harness, so
+// tests never sleep.
+package missiontest
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"lbox/pkg/clock"
+	"lbox/pkg/mission"
+)
+
+// waitTimeout bounds every wait of the harness so a hung mission fails the
+// test instead of blocking it forever
+const waitTimeout = 5 * time.Second
+
+// epoch is the time the fake clock of a Harness starts at
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// gate holds a started step until the harness releases it
+type gate struct {
+	step    string
+	release chan struct{}
+}
+
+// Harness drives a mission from a test. Missions without WithAutoAdvance are
+// navigated from the test with the methods of Mission, the harness runs the
+// steps that navigation starts
+type Harness struct {
+	t     testing.TB
+	m     *mission.Mission
+	clock *clock.Fake
+
+	stepping bool
+	pending  chan *gate
+	finished chan mission.StepRecord
+	free     chan struct{} // closed when steps no longer wait for release
+	freeOnce sync.Once
+	executed []string
+	records  []mission.StepRecord
+	mu       sync.Mutex
+}
+
+// New creates a harness for a mission of steps configured with opts. The
+// mission and the scripted steps among steps measure time on the fake clock
+// of the harness. The mission is stopped when the test ends
+func New(t testing.TB, steps []mission.StepDisposer, opts ...mission.Option) *Harness {
+	t.Helper()
+	h := &Harness{
+		t:        t,
+		clock:    clock.NewFake(epoch),
+		pending:  make(chan *gate),
+		finished: make(chan mission.StepRecord),
+		free:     make(chan struct{}),
+	}
+	for _, step := range steps {
+		if s, ok := step.(*ScriptedStep); ok {
+			s.useClock(h.clock)
+		}
+	}
+
+	options := append([]mission.Option{
+		mission.WithClock(h.clock),
+		mission.WithObserver(mission.ObserverFunc(h.observe)),
+	}, opts...)
+	m, err := mission.New(steps, options...)
+	if err != nil {
+		t.Fatalf("missiontest: creating mission: %v", err)
+	}
+	h.m = m
+	t.Cleanup(func() {
+		h.unblock()
+		m.Stop()
+	})
+	return h
+}
+
+// Mission returns the mission under test
+func (h *Harness) Mission() *mission.Mission {
+	return h.m
+}
+
+// Clock returns the fake clock driving the mission and its scripted steps
+func (h *Harness) Clock() *clock.Fake {
+	return h.clock
+}
+
+// Run starts the mission, lets every step run and returns the result
+func (h *Harness) Run() mission.Result {
+	h.t.Helper()
+	h.StartFree()
+	return h.Wait()
+}
+
+// Start starts the mission in step mode: every step waits before executing
+// until it is released by Next or StartStep
+func (h *Harness) Start() {
+	h.t.Helper()
+	h.mu.Lock()
+	h.stepping = true
+	h.mu.Unlock()
+	h.StartFree()
+}
+
+// StartFree starts the mission without holding its steps, use Wait to
+// collect the result
+func (h *Harness) StartFree() {
+	h.t.Helper()
+	if err := h.m.Start(); err != nil {
+		h.t.Fatalf("missiontest: starting mission: %v", err)
+	}
+}
+
+// Next releases the next waiting step, waits until it finished and returns
+// its record. It fails the test when the mission completes first
+func (h *Harness) Next() mission.StepRecord {
+	h.t.Helper()
+	if h.StartStep() == "" {
+		return mission.StepRecord{}
+	}
+	return h.AwaitStep()
+}
+
+// StartStep releases the next waiting step and returns its name without
+// waiting for it, so the test can advance the clock for a delayed step. It
+// fails the test when the mission completes first
+func (h *Harness) StartStep() string {
+	h.t.Helper()
+	select {
+	case g := <-h.pending:
+		close(g.release)
+		return g.step
+	case <-h.m.Done():
+		h.t.Fatalf("missiontest: mission completed with no step left to run, path %v", Path(h.m.Result()))
+	case <-time.After(waitTimeout):
+		h.t.Fatalf("missiontest: no step started within %v, executed %v", waitTimeout, h.Executed())
+	}
+	return ""
+}
+
+// AwaitStep waits until the step released by StartStep finished and returns
+// its record
+func (h *Harness) AwaitStep() mission.StepRecord {
+	h.t.Helper()
+	select {
+	case record := <-h.finished:
+		return record
+	case <-time.After(waitTimeout):
+		h.t.Fatalf("missiontest: step did not finish within %v, executed %v", waitTimeout, h.Executed())
+	}
+	return mission.StepRecord{}
+}
+
+// Wait releases the remaining steps, waits until the mission completed and
+// returns its result
+func (h *Harness) Wait() mission.Result {
+	h.t.Helper()
+	h.unblock()
+	select {
+	case <-h.m.Done():
+	case <-time.After(waitTimeout):
+		h.t.Fatalf("missiontest: mission did not complete within %v, executed %v", waitTimeout, h.Executed())
+	}
+	return h.m.Result()
+}
+
+// Executed returns the names of the steps started so far in order, including
+// steps that are still running
+func (h *Harness) Executed() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.executed...)
+}
+
+// Records returns the records of the steps finished so far in order
+func (h *Harness) Records() []mission.StepRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]mission.StepRecord(nil), h.records...)
+}
+
+// AssertExecuted fails the test unless exactly the named steps were started,
+// in order
+func (h *Harness) AssertExecuted(want ...string) {
+	h.t.Helper()
+	assertNames(h.t, "executed steps", h.Executed(), want)
+}
+
+// observe records the steps of the mission and holds them in step mode.
+// Events forwarded from sub-missions are ignored
+func (h *Harness) observe(_ *mission.Mission, e mission.Event) {
+	if len(e.Path) > 0 {
+		return
+	}
+
+	h.mu.Lock()
+	stepping := h.stepping
+	h.mu.Unlock()
+
+	switch e.Kind {
+	case mission.EventStepStarted:
+		if stepping {
+			h.hold(e.Step)
+		}
+		h.mu.Lock()
+		h.executed = append(h.executed, e.Step)
+		h.mu.Unlock()
+	case mission.EventStepFinished:
+		h.mu.Lock()
+		h.records = append(h.records, e.Record)
+		h.mu.Unlock()
+		if stepping {
+			select {
+			case h.finished <- e.Record:
+			case <-h.free:
+			}
+		}
+	}
+}
+
+// hold blocks a starting step until the harness releases it
+func (h *Harness) hold(step string) {
+	g := &gate{step: step, release: make(chan struct{})}
+	select {
+	case h.pending <- g:
+	case <-h.free:
+		return
+	}
+	select {
+	case <-g.release:
+	case <-h.free:
+	}
+}
+
+// unblock stops holding steps
+func (h *Harness) unblock() {
+	h.freeOnce.Do(func() {
+		close(h.free)
+	})
+}
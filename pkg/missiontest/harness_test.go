@@ -0,0 +1,150 @@
+package missiontest
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"lbox/pkg/mission"
+)
+
+var errBoom = errors.New("boom")
+
+// recorder is a testing.TB collecting failures instead of failing the test
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.Errorf(format, args...)
+}
+
+func steps(steps ...*ScriptedStep) []mission.StepDisposer {
+	list := make([]mission.StepDisposer, 0, len(steps))
+	for _, s := range steps {
+		list = append(list, s)
+	}
+	return list
+}
+
+func TestHarness_Run(t *testing.T) {
+	a, b, c := Step("a"), Step("b"), Step("c")
+	h := New(t, steps(a, b, c), mission.WithAutoAdvance())
+
+	result := h.Run()
+	AssertOutcome(t, result, mission.OutcomeSucceeded)
+	AssertPath(t, result, "a", "b", "c")
+	h.AssertExecuted("a", "b", "c")
+	for _, s := range []*ScriptedStep{a, b, c} {
+		AssertCalls(t, s, 1)
+	}
+	if records := h.Records(); len(records) != 3 {
+		t.Errorf("Expected 3 records, got %v", records)
+	}
+}
+
+func TestHarness_StepByStep(t *testing.T) {
+	h := New(t, steps(Step("a"), Step("b", Fail(errBoom)), Step("c")),
+		mission.WithAutoAdvance(), mission.WithStepPolicy("b", mission.JumpTo("c")))
+	h.Start()
+
+	if record := h.Next(); record.Name != "a" || record.Err != nil {
+		t.Errorf("Expected a to succeed, got %+v", record)
+	}
+	h.AssertExecuted("a")
+	if record := h.Next(); record.Name != "b" || !errors.Is(record.Err, errBoom) {
+		t.Errorf("Expected b to fail, got %+v", record)
+	}
+	h.AssertExecuted("a", "b")
+	if record := h.Next(); record.Name != "c" {
+		t.Errorf("Expected the error policy to jump to c, got %+v", record)
+	}
+
+	result := h.Wait()
+	AssertOutcome(t, result, mission.OutcomeSucceeded)
+	AssertPath(t, result, "a", "b", "c")
+	AssertStepFailed(t, result, "b", errBoom)
+}
+
+func TestHarness_ManualNavigation(t *testing.T) {
+	h := New(t, steps(Step("a"), Step("b"), Step("c")))
+	h.Start()
+
+	h.Next()
+	if err := h.Mission().Jump("c"); err != nil {
+		t.Fatalf("Jump failed: %v", err)
+	}
+	if record := h.Next(); record.Name != "c" {
+		t.Errorf("Expected the jump to run c, got %+v", record)
+	}
+	if err := h.Mission().GoNext(); err != nil {
+		t.Fatalf("GoNext failed: %v", err)
+	}
+	AssertPath(t, h.Wait(), "a", "c")
+}
+
+func TestHarness_Delay(t *testing.T) {
+	h := New(t, steps(Step("slow", Fail(errBoom).After(time.Hour))), mission.WithAutoAdvance())
+	h.Start()
+
+	if name := h.StartStep(); name != "slow" {
+		t.Fatalf("Expected slow to start, got %q", name)
+	}
+	h.Clock().BlockUntil(1)
+	h.Clock().Advance(time.Hour)
+	if record := h.AwaitStep(); !errors.Is(record.Err, errBoom) {
+		t.Errorf("Expected the delayed failure, got %+v", record)
+	}
+	AssertOutcome(t, h.Wait(), mission.OutcomeFailed)
+}
+
+func TestHarness_DelayTimeout(t *testing.T) {
+	h := New(t, steps(Step("slow", Delay(time.Hour))),
+		mission.WithAutoAdvance(), mission.WithStepTimeout("slow", time.Minute))
+	h.StartFree()
+
+	// The delay of the step and its timeout both run on the fake clock
+	h.Clock().BlockUntil(2)
+	h.Clock().Advance(time.Minute)
+	result := h.Wait()
+	AssertOutcome(t, result, mission.OutcomeFailed)
+	AssertStepFailed(t, result, "slow", mission.ErrStepTimeout)
+}
+
+func TestHarness_RetryBackoff(t *testing.T) {
+	s := Step("flaky", Fail(errBoom), Succeed())
+	h := New(t, steps(s), mission.WithAutoAdvance(),
+		mission.WithStepPolicy("flaky", mission.Retry(2, mission.ConstantBackoff(time.Hour))))
+	h.StartFree()
+
+	h.Clock().BlockUntil(1)
+	AssertCalls(t, s, 1)
+	h.Clock().Advance(time.Hour)
+	result := h.Wait()
+	AssertOutcome(t, result, mission.OutcomeSucceeded)
+	if record := result.Path[0]; record.Attempts != 2 || record.Duration != time.Hour {
+		t.Errorf("Expected 2 attempts an hour apart on the fake clock, got %+v", record)
+	}
+}
+
+func TestHarness_NextAfterCompletion(t *testing.T) {
+	r := &recorder{TB: t}
+	h := New(r, steps(Step("a")), mission.WithAutoAdvance())
+	h.Start()
+	h.Next()
+
+	if record := h.Next(); record.Name != "" {
+		t.Errorf("Expected no record, got %+v", record)
+	}
+	if len(r.failures) != 1 {
+		t.Errorf("Expected Next to fail the test once, got %v", r.failures)
+	}
+}
@@ -0,0 +1,150 @@
+package missiontest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"lbox/pkg/clock"
+	"lbox/pkg/mission"
+)
+
+// Outcome scripts what one invocation of a ScriptedStep does
+type Outcome struct {
+	delay      time.Duration
+	err        error
+	panicValue interface{}
+}
+
+// Succeed returns nil from the step
+func Succeed() Outcome {
+	return Outcome{}
+}
+
+// Fail returns err from the step
+func Fail(err error) Outcome {
+	return Outcome{err: err}
+}
+
+// Panic panics with v inside the step
+func Panic(v interface{}) Outcome {
+	return Outcome{panicValue: v}
+}
+
+// Delay succeeds once the clock of the step advanced by d
+func Delay(d time.Duration) Outcome {
+	return Outcome{delay: d}
+}
+
+// After waits until the clock of the step advanced by d before applying the
+// outcome. A cancelled step returns the error of its context instead
+func (o Outcome) After(d time.Duration) Outcome {
+	o.delay = d
+	return o
+}
+
+// Call records one invocation of a ScriptedStep
+type Call struct {
+	// Attempt is the attempt of the step execution, starting at 1
+	Attempt int
+	Tags    []interface{}
+	// Err is the error returned by the step, Panicked is set when it panicked
+	Err      error
+	Panicked bool
+}
+
+// ScriptedStep is a step playing back a list of outcomes, one per invocation.
+// The last outcome repeats once the list is exhausted, a step without
+// outcomes always succeeds
+type ScriptedStep struct {
+	name     string
+	outcomes []Outcome
+	clock    clock.TimerClock
+	calls    []Call
+	mu       sync.Mutex
+}
+
+// Step creates a scripted step. Delays use the system clock unless the step
+// is run by a Harness, which drives them with its fake clock
+func Step(name string, outcomes ...Outcome) *ScriptedStep {
+	return &ScriptedStep{name: name, outcomes: outcomes}
+}
+
+func (s *ScriptedStep) StepName() string {
+	return s.name
+}
+
+func (s *ScriptedStep) Dispose(m *mission.Mission, tags []interface{}) error {
+	return s.DisposeContext(context.Background(), m, tags)
+}
+
+func (s *ScriptedStep) DisposeContext(ctx context.Context, _ *mission.Mission, tags []interface{}) error {
+	s.mu.Lock()
+	outcome := Succeed()
+	if n := len(s.calls); n < len(s.outcomes) {
+		outcome = s.outcomes[n]
+	} else if len(s.outcomes) > 0 {
+		outcome = s.outcomes[len(s.outcomes)-1]
+	}
+	call := Call{Attempt: 1, Tags: tags}
+	if info, ok := mission.StepInfoFrom(ctx); ok {
+		call.Attempt = info.Attempt
+	}
+	index := len(s.calls)
+	s.calls = append(s.calls, call)
+	c := s.clock
+	s.mu.Unlock()
+
+	if c == nil {
+		c = clock.Real{}
+	}
+	err := wait(ctx, c, outcome.delay)
+	if err == nil {
+		err = outcome.err
+	}
+	s.mu.Lock()
+	s.calls[index].Err = err
+	s.calls[index].Panicked = err == nil && outcome.panicValue != nil
+	s.mu.Unlock()
+
+	if err == nil && outcome.panicValue != nil {
+		panic(outcome.panicValue)
+	}
+	return err
+}
+
+// Calls returns the invocations of the step so far
+func (s *ScriptedStep) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Call(nil), s.calls...)
+}
+
+// CallCount returns the number of invocations of the step
+func (s *ScriptedStep) CallCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.calls)
+}
+
+// useClock sets the clock delays are measured on
+func (s *ScriptedStep) useClock(c clock.TimerClock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
+
+// wait blocks until c advanced by d or ctx is done
+func wait(ctx context.Context, c clock.TimerClock, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := c.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
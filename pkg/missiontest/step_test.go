@@ -0,0 +1,65 @@
+package missiontest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"lbox/pkg/mission"
+)
+
+func TestScriptedStep_Outcomes(t *testing.T) {
+	s := Step("flaky", Fail(errBoom), Fail(errBoom), Succeed())
+	h := New(t, steps(s), mission.WithAutoAdvance(),
+		mission.WithStepPolicy("flaky", mission.Retry(3, mission.ConstantBackoff(0))))
+
+	result := h.Run()
+	AssertOutcome(t, result, mission.OutcomeSucceeded)
+	AssertCalls(t, s, 3)
+	calls := s.Calls()
+	for i, call := range calls {
+		if call.Attempt != i+1 {
+			t.Errorf("Expected call %d to be attempt %d, got %d", i, i+1, call.Attempt)
+		}
+	}
+	if !errors.Is(calls[0].Err, errBoom) || calls[2].Err != nil {
+		t.Errorf("Expected the scripted errors to be recorded, got %+v", calls)
+	}
+}
+
+func TestScriptedStep_RepeatsLastOutcome(t *testing.T) {
+	s := Step("failing", Fail(errBoom))
+	for i := 0; i < 3; i++ {
+		if err := s.Dispose(nil, nil); !errors.Is(err, errBoom) {
+			t.Errorf("Call %d: expected errBoom, got %v", i, err)
+		}
+	}
+	if err := Step("empty").Dispose(nil, nil); err != nil {
+		t.Errorf("Expected a step without outcomes to succeed, got %v", err)
+	}
+}
+
+func TestScriptedStep_Panic(t *testing.T) {
+	s := Step("panicky", Panic("kaboom"))
+	result := New(t, steps(Step("a"), s), mission.WithAutoAdvance()).Run()
+
+	AssertOutcome(t, result, mission.OutcomeFailed)
+	AssertPath(t, result, "a", "panicky")
+	var panicErr *mission.PanicError
+	if !errors.As(result.Path[1].Err, &panicErr) || panicErr.Value != "kaboom" {
+		t.Errorf("Expected a PanicError, got %v", result.Path[1].Err)
+	}
+	if calls := s.Calls(); len(calls) != 1 || !calls[0].Panicked {
+		t.Errorf("Expected the panic to be recorded, got %+v", calls)
+	}
+}
+
+func TestScriptedStep_DelayCancelled(t *testing.T) {
+	s := Step("slow", Delay(time.Hour))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.DisposeContext(ctx, nil, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the delay to observe cancellation, got %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCron_Next(t *testing.T) {
+	// Monday 2024-01-01 10:30
+	after := time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, 1, 1, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 1, 1, 10, 45, 0, 0, time.UTC)},
+		{"0 9-17 * * *", time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC)},
+		{"0 9 * * *", time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * fri", time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 mar *", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 13 * 5", time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{"5,10 10 * * *", time.Date(2024, 1, 2, 10, 5, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+	} {
+		s, err := Cron(tc.expr)
+		if err != nil {
+			t.Fatalf("Cron(%q) failed: %v", tc.expr, err)
+		}
+		if got := s.Next(after); !got.Equal(tc.want) {
+			t.Errorf("Cron(%q).Next = %v, want %v", tc.expr, got, tc.want)
+		}
+	}
+}
+
+func TestCron_NeverDue(t *testing.T) {
+	s, err := Cron("0 0 31 2 *")
+	if err != nil {
+		t.Fatalf("Cron failed: %v", err)
+	}
+	if next := s.Next(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)); !next.IsZero() {
+		t.Errorf("Expected no run on February 31st, got %v", next)
+	}
+}
+
+func TestCron_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * foo *",
+		"*/0 * * * *",
+		"10-5 * * * *",
+	} {
+		if _, err := Cron(expr); err == nil {
+			t.Errorf("Expected Cron(%q) to fail", expr)
+		}
+	}
+}
@@ -0,0 +1,501 @@
+// Package scheduler starts missions on cron expressions, fixed intervals or
+// after a delay. Time is read from a clock.TimerClock, so schedules can be tested
+// deterministically with clock.Fake.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"lbox/pkg/clock"
+	"lbox/pkg/mission"
+)
+
+// Schedule computes the start times of an entry
+type Schedule interface {
+	// Next returns the first start time after after, or the zero time when
+	// the schedule has no more runs
+	Next(after time.Time) time.Time
+}
+
+// ScheduleFunc adapts a function to the Schedule interface
+type ScheduleFunc func(after time.Time) time.Time
+
+func (f ScheduleFunc) Next(after time.Time) time.Time {
+	return f(after)
+}
+
+// Every starts a run every d, the first one d after the entry was added. A
+// non-positive d is rejected by Scheduler.Add
+func Every(d time.Duration) Schedule {
+	return ScheduleFunc(func(after time.Time) time.Time {
+		return after.Add(d)
+	})
+}
+
+// At starts a single run at t
+func At(t time.Time) Schedule {
+	return ScheduleFunc(func(after time.Time) time.Time {
+		if after.Before(t) {
+			return t
+		}
+		return time.Time{}
+	})
+}
+
+// Delay starts a single run d after the entry was added
+func Delay(d time.Duration) Schedule {
+	return &delay{d: d}
+}
+
+type delay struct {
+	d     time.Duration
+	at    time.Time
+	armed bool
+	mu    sync.Mutex
+}
+
+func (s *delay) Next(after time.Time) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.armed {
+		s.armed = true
+		s.at = after.Add(s.d)
+	}
+	if after.Before(s.at) {
+		return s.at
+	}
+	return time.Time{}
+}
+
+// Overlap selects what happens when a run is due while the previous run of the
+// same entry is still going
+type Overlap int
+
+const (
+	// OverlapSkip drops the new run
+	OverlapSkip Overlap = iota
+	// OverlapQueue starts the new run once the previous runs completed
+	OverlapQueue
+	// OverlapReplace stops the running mission and starts the new run
+	OverlapReplace
+)
+
+// Missed selects what happens to runs that became due while the scheduler
+// could not start them, for example after the process was suspended
+type Missed int
+
+const (
+	// MissedRunOnce starts a single run for all missed ones
+	MissedRunOnce Missed = iota
+	// MissedRunAll starts one run per missed start time
+	MissedRunAll
+	// MissedSkip drops runs later than the grace period, see WithGrace
+	MissedSkip
+)
+
+// maxCatchUp bounds the runs started at once by MissedRunAll
+const maxCatchUp = 100
+
+// ErrDuplicateEntry is returned by Add for a name already in use
+var ErrDuplicateEntry = errors.New("scheduler entry already exists")
+
+// ErrInvalidSchedule is returned by Add, and reported through OnError while
+// running, for a schedule whose next start time does not move forward
+var ErrInvalidSchedule = errors.New("schedule does not advance")
+
+// Options configures a Scheduler, zero values select the defaults
+type Options struct {
+	// Clock is the time source, defaults to the system clock
+	Clock clock.TimerClock
+	// OnError is called when a mission cannot be created or a schedule stops
+	// advancing, defaults to ignoring the error
+	OnError func(entry string, err error)
+}
+
+// EntryOption configures an entry added to a Scheduler
+type EntryOption func(e *entry)
+
+// WithOverlap sets the overlap policy of the entry, the default is
+// OverlapSkip
+func WithOverlap(policy Overlap) EntryOption {
+	return func(e *entry) {
+		e.overlap = policy
+	}
+}
+
+// WithMissed sets the missed-run policy of the entry, the default is
+// MissedRunOnce
+func WithMissed(policy Missed) EntryOption {
+	return func(e *entry) {
+		e.missed = policy
+	}
+}
+
+// WithGrace sets how late a run may start under MissedSkip, defaults to one
+// second
+func WithGrace(d time.Duration) EntryOption {
+	return func(e *entry) {
+		e.grace = d
+	}
+}
+
+// Status describes an entry of a Scheduler
+type Status struct {
+	Name string
+	// Next is the next start time, zero when the schedule has ended
+	Next    time.Time
+	Running int
+	Queued  int
+	// Runs counts the started missions, Skipped the runs dropped by the
+	// overlap or missed-run policy
+	Runs    int
+	Skipped int
+}
+
+// entry is a schedule with the missions it started
+type entry struct {
+	name     string
+	schedule Schedule
+	build    func() (*mission.Mission, error)
+	overlap  Overlap
+	missed   Missed
+	grace    time.Duration
+
+	next     time.Time
+	running  []*mission.Mission
+	starting int // missions being built outside the lock
+	queued   int
+	runs     int
+	skipped  int
+}
+
+// Scheduler starts the missions of its entries when they are due
+type Scheduler struct {
+	options Options
+	entries map[string]*entry
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wake    chan struct{}
+	started bool
+	stopped chan struct{} // closed when the loop exits
+	runs    sync.WaitGroup
+	mu      sync.Mutex
+}
+
+// New creates a scheduler, call Start to begin scheduling
+func New(options Options) *Scheduler {
+	if options.Clock == nil {
+		options.Clock = clock.Real{}
+	}
+	if options.OnError == nil {
+		options.OnError = func(string, error) {}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		options: options,
+		entries: make(map[string]*entry),
+		ctx:     ctx,
+		cancel:  cancel,
+		wake:    make(chan struct{}, 1),
+		stopped: make(chan struct{}),
+	}
+}
+
+// Add schedules the missions created by build under name. The schedule is
+// evaluated from the current time of the clock
+func (s *Scheduler) Add(name string, schedule Schedule, build func() (*mission.Mission, error), opts ...EntryOption) error {
+	e := &entry{name: name, schedule: schedule, build: build, grace: time.Second}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.entries[name]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateEntry, name)
+	}
+	now := s.options.Clock.Now()
+	next := schedule.Next(now)
+	if !next.IsZero() && !next.After(now) {
+		return fmt.Errorf("%w: %s", ErrInvalidSchedule, name)
+	}
+	e.next = next
+	s.entries[name] = e
+	s.signal()
+	return nil
+}
+
+// Remove deletes the entry, its running missions are not stopped
+func (s *Scheduler) Remove(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, name)
+	s.signal()
+}
+
+// Status returns the state of the entries sorted by name
+func (s *Scheduler) Status() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]Status, 0, len(s.entries))
+	for _, e := range s.entries {
+		statuses = append(statuses, Status{
+			Name:    e.name,
+			Next:    e.next,
+			Running: len(e.running),
+			Queued:  e.queued,
+			Runs:    e.runs,
+			Skipped: e.skipped,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// Start begins scheduling on a background goroutine
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+	go s.loop()
+}
+
+// Stop ends scheduling, stops the running missions and waits until they
+// completed or ctx is done
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	started := s.started
+	s.mu.Unlock()
+
+	s.cancel()
+	if started {
+		<-s.stopped
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.runs.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// signal wakes the loop to recompute its timer
+func (s *Scheduler) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// loop sleeps until the earliest entry is due and starts its runs
+func (s *Scheduler) loop() {
+	defer close(s.stopped)
+	for {
+		s.mu.Lock()
+		var earliest time.Time
+		for _, e := range s.entries {
+			if !e.next.IsZero() && (earliest.IsZero() || e.next.Before(earliest)) {
+				earliest = e.next
+			}
+		}
+		s.mu.Unlock()
+
+		var timer clock.Timer
+		var fired <-chan time.Time
+		if !earliest.IsZero() {
+			timer = s.options.Clock.NewTimer(earliest.Sub(s.options.Clock.Now()))
+			fired = timer.C()
+		}
+
+		select {
+		case <-fired:
+			s.fire()
+		case <-s.wake:
+		case <-s.ctx.Done():
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if s.ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// failure is an error reported through OnError once the lock is released
+type failure struct {
+	entry string
+	err   error
+}
+
+// fire starts the runs of every due entry. Missions are built and started
+// without the lock held, so build functions may use the scheduler
+func (s *Scheduler) fire() {
+	s.mu.Lock()
+	var launches []*entry
+	var stops []*mission.Mission
+	var failures []failure
+
+	now := s.options.Clock.Now()
+	for _, e := range s.entries {
+		if e.next.IsZero() || e.next.After(now) {
+			continue
+		}
+
+		// Collect the start times that passed since the last run. A schedule
+		// that does not move forward ends after this run
+		due := []time.Time{e.next}
+		next := e.schedule.Next(e.next)
+		for !next.IsZero() && !next.After(now) {
+			if !next.After(due[len(due)-1]) {
+				failures = append(failures, failure{e.name, fmt.Errorf("%w: %s", ErrInvalidSchedule, e.name)})
+				next = time.Time{}
+				break
+			}
+			due = append(due, next)
+			next = e.schedule.Next(next)
+		}
+		e.next = next
+
+		runs := 1
+		switch e.missed {
+		case MissedRunAll:
+			runs = len(due)
+			if runs > maxCatchUp {
+				e.skipped += runs - maxCatchUp
+				runs = maxCatchUp
+			}
+		case MissedSkip:
+			if now.Sub(due[len(due)-1]) > e.grace {
+				runs = 0
+			}
+			e.skipped += len(due) - runs
+		default:
+			e.skipped += len(due) - 1
+		}
+		for i := 0; i < runs; i++ {
+			launch, replaced := s.trigger(e)
+			if launch {
+				launches = append(launches, e)
+			}
+			stops = append(stops, replaced...)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, f := range failures {
+		s.options.OnError(f.entry, f.err)
+	}
+	for _, m := range stops {
+		m.Stop()
+	}
+	for _, e := range launches {
+		s.launch(e)
+	}
+}
+
+// trigger applies the overlap policy to a due run of e. It reports whether a
+// mission must be launched and returns the missions it replaces
+// Note: This method assumes the caller holds the lock
+func (s *Scheduler) trigger(e *entry) (bool, []*mission.Mission) {
+	var replaced []*mission.Mission
+	if e.active() > 0 {
+		switch e.overlap {
+		case OverlapQueue:
+			e.queued++
+			return false, nil
+		case OverlapReplace:
+			replaced = append(replaced, e.running...)
+		default:
+			e.skipped++
+			return false, nil
+		}
+	}
+	e.starting++
+	return true, replaced
+}
+
+// launch builds and starts a mission of e reserved by trigger
+// Note: This method must be called without the lock held
+func (s *Scheduler) launch(e *entry) {
+	m, err := e.build()
+
+	s.mu.Lock()
+	e.starting--
+	if err != nil {
+		s.mu.Unlock()
+		s.options.OnError(e.name, err)
+		return
+	}
+	e.running = append(e.running, m)
+	e.runs++
+	s.runs.Add(1)
+	s.mu.Unlock()
+
+	m.OnComplete(func(mission.Result) {
+		s.completed(e, m)
+	})
+	if err := m.StartContext(s.ctx); err != nil {
+		// The mission was already running elsewhere, its completion is not
+		// one of our runs
+		s.mu.Lock()
+		if e.remove(m) {
+			e.runs--
+			s.runs.Done()
+		}
+		s.mu.Unlock()
+		s.options.OnError(e.name, err)
+	}
+}
+
+// completed removes a finished mission and starts a queued run
+func (s *Scheduler) completed(e *entry, m *mission.Mission) {
+	s.mu.Lock()
+	if !e.remove(m) {
+		s.mu.Unlock()
+		return
+	}
+	defer s.runs.Done()
+	launch := e.queued > 0 && e.active() == 0 && s.ctx.Err() == nil
+	if launch {
+		e.queued--
+		e.starting++
+	}
+	s.mu.Unlock()
+
+	if launch {
+		s.launch(e)
+	}
+}
+
+// remove drops m from the running missions of e and reports whether it was
+// running
+func (e *entry) remove(m *mission.Mission) bool {
+	for i, running := range e.running {
+		if running == m {
+			e.running = append(e.running[:i], e.running[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// active counts the missions of e that are running or being built
+func (e *entry) active() int {
+	return len(e.running) + e.starting
+}
@@ -0,0 +1,381 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"lbox/pkg/clock"
+	"lbox/pkg/mission"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// gateStep signals started and blocks until gate is closed or the mission is
+// cancelled
+type gateStep struct {
+	started chan<- struct{}
+	gate    <-chan struct{}
+}
+
+func (s *gateStep) StepName() string {
+	return "gate"
+}
+
+func (s *gateStep) Dispose(m *mission.Mission, tags []interface{}) error {
+	return s.DisposeContext(context.Background(), m, tags)
+}
+
+func (s *gateStep) DisposeContext(ctx context.Context, _ *mission.Mission, _ []interface{}) error {
+	s.started <- struct{}{}
+	select {
+	case <-s.gate:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// gated returns a mission factory whose missions block on gate
+func gated(started chan<- struct{}, gate <-chan struct{}) func() (*mission.Mission, error) {
+	return func() (*mission.Mission, error) {
+		return mission.New([]mission.StepDisposer{&gateStep{started: started, gate: gate}}, mission.WithAutoAdvance())
+	}
+}
+
+// closed returns a closed gate
+func closed() chan struct{} {
+	gate := make(chan struct{})
+	close(gate)
+	return gate
+}
+
+// startScheduler starts s with a single entry and waits until its timer is armed
+func startScheduler(t *testing.T, fake *clock.Fake, schedule Schedule, build func() (*mission.Mission, error), opts ...EntryOption) *Scheduler {
+	t.Helper()
+	s := New(Options{Clock: fake})
+	if err := s.Add("job", schedule, build, opts...); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	s.Start()
+	t.Cleanup(func() {
+		s.Stop(context.Background())
+	})
+	fake.BlockUntil(1)
+	return s
+}
+
+// advance moves the clock and waits until the scheduler handled the timer
+func advance(fake *clock.Fake, d time.Duration) {
+	fake.Advance(d)
+	fake.BlockUntil(1)
+}
+
+func status(s *Scheduler) Status {
+	return s.Status()[0]
+}
+
+func TestScheduler_Every(t *testing.T) {
+	fake := clock.NewFake(start)
+	started := make(chan struct{}, 2)
+	s := startScheduler(t, fake, Every(time.Minute), gated(started, closed()))
+
+	for i := 0; i < 2; i++ {
+		advance(fake, time.Minute)
+		<-started
+	}
+	if st := status(s); st.Runs != 2 || !st.Next.Equal(start.Add(3*time.Minute)) {
+		t.Errorf("Expected 2 runs and the next one at 3m, got %+v", st)
+	}
+}
+
+func TestScheduler_Delay(t *testing.T) {
+	fake := clock.NewFake(start)
+	started := make(chan struct{}, 1)
+	s := New(Options{Clock: fake})
+	if err := s.Add("once", Delay(time.Hour), gated(started, closed())); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	s.Start()
+	fake.BlockUntil(1)
+	fake.Advance(time.Hour)
+	<-started
+
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if st := status(s); st.Runs != 1 || !st.Next.IsZero() {
+		t.Errorf("Expected a single run and no next one, got %+v", st)
+	}
+}
+
+func TestScheduler_Overlap(t *testing.T) {
+	t.Run("skip", func(t *testing.T) {
+		fake := clock.NewFake(start)
+		started := make(chan struct{}, 2)
+		s := startScheduler(t, fake, Every(time.Minute), gated(started, make(chan struct{})))
+
+		advance(fake, time.Minute)
+		<-started
+		advance(fake, time.Minute)
+		if st := status(s); st.Runs != 1 || st.Skipped != 1 || st.Running != 1 {
+			t.Errorf("Expected the overlapping run to be skipped, got %+v", st)
+		}
+	})
+
+	t.Run("queue", func(t *testing.T) {
+		fake := clock.NewFake(start)
+		started := make(chan struct{}, 2)
+		gate := make(chan struct{})
+		s := startScheduler(t, fake, Every(time.Minute), gated(started, gate), WithOverlap(OverlapQueue))
+
+		advance(fake, time.Minute)
+		<-started
+		advance(fake, time.Minute)
+		if st := status(s); st.Runs != 1 || st.Queued != 1 {
+			t.Errorf("Expected the overlapping run to be queued, got %+v", st)
+		}
+		close(gate)
+		<-started
+		if st := status(s); st.Runs != 2 || st.Queued != 0 {
+			t.Errorf("Expected the queued run to start, got %+v", st)
+		}
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		fake := clock.NewFake(start)
+		started := make(chan struct{}, 2)
+		var first *mission.Mission
+		build := gated(started, make(chan struct{}))
+		s := startScheduler(t, fake, Every(time.Minute), func() (*mission.Mission, error) {
+			m, err := build()
+			if first == nil {
+				first = m
+			}
+			return m, err
+		}, WithOverlap(OverlapReplace))
+
+		advance(fake, time.Minute)
+		<-started
+		advance(fake, time.Minute)
+		<-started
+		<-first.Done()
+		if outcome := first.Result().Outcome; outcome != mission.OutcomeStopped {
+			t.Errorf("Expected the first run to be stopped, got %v", outcome)
+		}
+		if st := status(s); st.Runs != 2 || st.Skipped != 0 {
+			t.Errorf("Expected two runs, got %+v", st)
+		}
+	})
+}
+
+func TestScheduler_Missed(t *testing.T) {
+	t.Run("run once", func(t *testing.T) {
+		fake := clock.NewFake(start)
+		started := make(chan struct{}, 5)
+		s := startScheduler(t, fake, Every(time.Minute), gated(started, closed()))
+
+		advance(fake, 5*time.Minute)
+		<-started
+		if st := status(s); st.Runs != 1 || st.Skipped != 4 || !st.Next.Equal(start.Add(6*time.Minute)) {
+			t.Errorf("Expected one run for the missed ones, got %+v", st)
+		}
+	})
+
+	t.Run("run all", func(t *testing.T) {
+		fake := clock.NewFake(start)
+		started := make(chan struct{}, 5)
+		s := startScheduler(t, fake, Every(time.Minute), gated(started, closed()),
+			WithMissed(MissedRunAll), WithOverlap(OverlapQueue))
+
+		advance(fake, 5*time.Minute)
+		for i := 0; i < 5; i++ {
+			<-started
+		}
+		if st := status(s); st.Runs != 5 || st.Skipped != 0 {
+			t.Errorf("Expected one run per missed start, got %+v", st)
+		}
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		fake := clock.NewFake(start)
+		started := make(chan struct{}, 1)
+		s := startScheduler(t, fake, Every(time.Minute), gated(started, closed()),
+			WithMissed(MissedSkip), WithGrace(10*time.Second))
+
+		advance(fake, 90*time.Second)
+		if st := status(s); st.Runs != 0 || st.Skipped != 1 {
+			t.Errorf("Expected the late run to be skipped, got %+v", st)
+		}
+		advance(fake, 35*time.Second)
+		<-started
+		if st := status(s); st.Runs != 1 {
+			t.Errorf("Expected the run within the grace period to start, got %+v", st)
+		}
+	})
+}
+
+func TestScheduler_Errors(t *testing.T) {
+	fake := clock.NewFake(start)
+	failed := make(chan string, 1)
+	s := New(Options{Clock: fake, OnError: func(entry string, err error) {
+		failed <- entry
+	}})
+
+	build := func() (*mission.Mission, error) {
+		return nil, errors.New("boom")
+	}
+	if err := s.Add("broken", Every(time.Minute), build); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := s.Add("broken", Every(time.Minute), build); !errors.Is(err, ErrDuplicateEntry) {
+		t.Errorf("Expected ErrDuplicateEntry, got %v", err)
+	}
+
+	s.Start()
+	fake.BlockUntil(1)
+	fake.Advance(time.Minute)
+	if entry := <-failed; entry != "broken" {
+		t.Errorf("Expected the error of entry broken, got %q", entry)
+	}
+
+	s.Remove("broken")
+	if err := s.Stop(context.Background()); err != nil {
+		t.Errorf("Stop failed: %v", err)
+	}
+	if len(s.Status()) != 0 {
+		t.Errorf("Expected no entries after Remove, got %+v", s.Status())
+	}
+}
+
+func TestScheduler_MissionAlreadyRunning(t *testing.T) {
+	fake := clock.NewFake(start)
+	failed := make(chan error, 1)
+	s := New(Options{Clock: fake, OnError: func(_ string, err error) {
+		failed <- err
+	}})
+
+	started := make(chan struct{}, 1)
+	gate := make(chan struct{})
+	shared, err := gated(started, gate)()
+	if err != nil {
+		t.Fatalf("Creating mission failed: %v", err)
+	}
+	if err := shared.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	<-started
+
+	build := func() (*mission.Mission, error) {
+		return shared, nil
+	}
+	if err := s.Add("shared", Every(time.Minute), build); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	s.Start()
+	fake.BlockUntil(1)
+	fake.Advance(time.Minute)
+	if err := <-failed; !errors.Is(err, mission.ErrAlreadyRunning) {
+		t.Errorf("Expected ErrAlreadyRunning, got %v", err)
+	}
+	if st := status(s); st.Running != 0 || st.Runs != 0 {
+		t.Errorf("Expected the rejected run not to be counted, got %+v", st)
+	}
+
+	close(gate)
+	<-shared.Done()
+	if err := s.Stop(context.Background()); err != nil {
+		t.Errorf("Stop failed: %v", err)
+	}
+}
+
+func TestScheduler_StopCancelsRuns(t *testing.T) {
+	fake := clock.NewFake(start)
+	started := make(chan struct{}, 1)
+	var run *mission.Mission
+	build := gated(started, make(chan struct{}))
+	s := New(Options{Clock: fake})
+	s.Add("job", Every(time.Minute), func() (*mission.Mission, error) {
+		m, err := build()
+		run = m
+		return m, err
+	})
+	s.Start()
+	fake.BlockUntil(1)
+	fake.Advance(time.Minute)
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := s.Stop(ctx); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if outcome := run.Result().Outcome; outcome != mission.OutcomeStopped {
+		t.Errorf("Expected the running mission to be stopped, got %v", outcome)
+	}
+}
+
+func TestScheduler_InvalidSchedule(t *testing.T) {
+	fake := clock.NewFake(start)
+	failed := make(chan error, 1)
+	s := New(Options{Clock: fake, OnError: func(_ string, err error) {
+		failed <- err
+	}})
+	t.Cleanup(func() {
+		s.Stop(context.Background())
+	})
+
+	for _, d := range []time.Duration{0, -time.Minute} {
+		if err := s.Add("every", Every(d), gated(nil, closed())); !errors.Is(err, ErrInvalidSchedule) {
+			t.Errorf("Every(%v): expected ErrInvalidSchedule, got %v", d, err)
+		}
+	}
+
+	// Advances once, then keeps returning the same time
+	stuck := ScheduleFunc(func(after time.Time) time.Time {
+		return start.Add(time.Minute)
+	})
+	started := make(chan struct{}, 1)
+	if err := s.Add("stuck", stuck, gated(started, closed())); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	s.Start()
+	fake.BlockUntil(1)
+	fake.Advance(time.Minute)
+	<-started
+	if err := <-failed; !errors.Is(err, ErrInvalidSchedule) {
+		t.Errorf("Expected ErrInvalidSchedule, got %v", err)
+	}
+	if st := status(s); !st.Next.IsZero() || st.Runs != 1 {
+		t.Errorf("Expected the stuck schedule to end after one run, got %+v", st)
+	}
+}
+
+func TestScheduler_BuildUsesScheduler(t *testing.T) {
+	fake := clock.NewFake(start)
+	started := make(chan struct{}, 1)
+	s := New(Options{Clock: fake})
+	t.Cleanup(func() {
+		s.Stop(context.Background())
+	})
+
+	build := gated(started, closed())
+	if err := s.Add("once", Every(time.Minute), func() (*mission.Mission, error) {
+		if len(s.Status()) != 1 {
+			t.Errorf("Expected Status to list the entry, got %+v", s.Status())
+		}
+		s.Remove("once")
+		return build()
+	}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	s.Start()
+	fake.BlockUntil(1)
+	fake.Advance(time.Minute)
+	<-started
+
+	if statuses := s.Status(); len(statuses) != 0 {
+		t.Errorf("Expected the build function to remove the entry, got %+v", statuses)
+	}
+}
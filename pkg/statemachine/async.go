@@ -0,0 +1,231 @@
+package statemachine
+
+import (
+    "context"
+    "errors"
+    "sync"
+    "sync/atomic"
+)
+
+// ErrMailboxFull is returned when an event cannot be queued because the
+// mailbox is full and the backpressure mode does not allow blocking
+var ErrMailboxFull = errors.New("state machine mailbox full")
+
+// ErrShutdown is returned for events sent after Shutdown was called
+var ErrShutdown = errors.New("state machine shut down")
+
+// Backpressure selects how Send behaves when the mailbox is full
+type Backpressure int
+
+const (
+    // BackpressureBlock waits until the loop frees a slot
+    BackpressureBlock Backpressure = iota
+    // BackpressureDrop discards the event and counts it as dropped
+    BackpressureDrop
+    // BackpressureError rejects the event with ErrMailboxFull
+    BackpressureError
+)
+
+// ErrInvalidEvent is returned for an Event setting both or neither of Fire
+// and Target
+var ErrInvalidEvent = errors.New("event must set exactly one of Fire and Target")
+
+// Event is processed by the loop. It either fires the event named Fire or
+// changes directly to the state named Target
+type Event struct {
+    Fire    string
+    Target  string
+    Payload interface{}
+}
+
+// validate checks that ev names exactly one of an event and a state
+func (ev Event) validate() error {
+    if (ev.Fire == "") == (ev.Target == "") {
+        return ErrInvalidEvent
+    }
+    return nil
+}
+
+// AsyncOptions configures an AsyncStateMachine
+type AsyncOptions struct {
+    // MailboxSize bounds the number of queued events, defaults to 64
+    MailboxSize int
+    // Backpressure applies to Send when the mailbox is full
+    Backpressure Backpressure
+    // OnError receives failures of events sent without waiting
+    OnError func(ev Event, err error)
+}
+
+// AsyncStateMachine processes events on a dedicated loop goroutine so hooks
+// never run on the caller's goroutine. Transitions only go through the
+// mailbox, the wrapped machine is exposed through its read accessors
+type AsyncStateMachine struct {
+    sm      *StateMachine
+    opts    AsyncOptions
+    mailbox chan envelope
+    dropped uint64
+    closed  bool
+    mu      sync.RWMutex  // guards closed against concurrent sends
+    quit    chan struct{} // closed by Shutdown to release blocked senders
+    once    sync.Once
+    done    chan struct{}
+}
+
+// envelope carries an event through the mailbox, result is nil for Send
+type envelope struct {
+    event  Event
+    result chan error
+}
+
+// NewAsyncStateMachine wraps sm and starts its event loop
+func NewAsyncStateMachine(sm *StateMachine, opts AsyncOptions) *AsyncStateMachine {
+    if opts.MailboxSize <= 0 {
+        opts.MailboxSize = 64
+    }
+    a := &AsyncStateMachine{
+        sm:      sm,
+        opts:    opts,
+        mailbox: make(chan envelope, opts.MailboxSize),
+        quit:    make(chan struct{}),
+        done:    make(chan struct{}),
+    }
+    go a.loop()
+    return a
+}
+
+// Send enqueues ev without waiting for it to be processed
+func (a *AsyncStateMachine) Send(ev Event) error {
+    if err := ev.validate(); err != nil {
+        return err
+    }
+
+    a.mu.RLock()
+    defer a.mu.RUnlock()
+
+    if a.closed {
+        return ErrShutdown
+    }
+
+    env := envelope{event: ev}
+    switch a.opts.Backpressure {
+    case BackpressureBlock:
+        select {
+        case a.mailbox <- env:
+            return nil
+        case <-a.quit:
+            return ErrShutdown
+        }
+    case BackpressureDrop:
+        select {
+        case a.mailbox <- env:
+        default:
+            atomic.AddUint64(&a.dropped, 1)
+        }
+        return nil
+    default:
+        select {
+        case a.mailbox <- env:
+            return nil
+        default:
+            return ErrMailboxFull
+        }
+    }
+}
+
+// SendAndWait enqueues ev and returns the outcome of its transition. A full
+// mailbox is waited on in block mode and reported as ErrMailboxFull otherwise
+func (a *AsyncStateMachine) SendAndWait(ctx context.Context, ev Event) error {
+    if err := ev.validate(); err != nil {
+        return err
+    }
+    env := envelope{event: ev, result: make(chan error, 1)}
+    if err := a.enqueue(ctx, env); err != nil {
+        return err
+    }
+
+    select {
+    case err := <-env.result:
+        return err
+    case <-ctx.Done():
+        return ctx.Err()
+    }
+}
+
+// enqueue places env in the mailbox for SendAndWait
+func (a *AsyncStateMachine) enqueue(ctx context.Context, env envelope) error {
+    a.mu.RLock()
+    defer a.mu.RUnlock()
+
+    if a.closed {
+        return ErrShutdown
+    }
+
+    if a.opts.Backpressure == BackpressureBlock {
+        select {
+        case a.mailbox <- env:
+            return nil
+        case <-a.quit:
+            return ErrShutdown
+        case <-ctx.Done():
+            return ctx.Err()
+        }
+    }
+
+    select {
+    case a.mailbox <- env:
+        return nil
+    default:
+        return ErrMailboxFull
+    }
+}
+
+// GetCurrentState returns the current state of the wrapped machine
+func (a *AsyncStateMachine) GetCurrentState() State {
+    return a.sm.GetCurrentState()
+}
+
+// IsRunning returns whether the wrapped machine is running
+func (a *AsyncStateMachine) IsRunning() bool {
+    return a.sm.IsRunning()
+}
+
+// Dropped returns how many events were discarded by BackpressureDrop
+func (a *AsyncStateMachine) Dropped() uint64 {
+    return atomic.LoadUint64(&a.dropped)
+}
+
+// Shutdown stops accepting events and waits until the queued ones have been
+// processed or ctx is done. Senders blocked on a full mailbox give up with
+// ErrShutdown, so the mailbox can be closed without waiting for the loop
+func (a *AsyncStateMachine) Shutdown(ctx context.Context) error {
+    a.once.Do(func() {
+        close(a.quit)
+    })
+
+    a.mu.Lock()
+    if !a.closed {
+        a.closed = true
+        close(a.mailbox)
+    }
+    a.mu.Unlock()
+
+    select {
+    case <-a.done:
+        return nil
+    case <-ctx.Done():
+        return ctx.Err()
+    }
+}
+
+// loop applies queued events one at a time until the mailbox is closed
+func (a *AsyncStateMachine) loop() {
+    defer close(a.done)
+    for env := range a.mailbox {
+        err := a.sm.dispatch(env.event)
+        if env.result != nil {
+            env.result <- err
+        } else if err != nil && a.opts.OnError != nil {
+            a.opts.OnError(env.event, err)
+        }
+    }
+}
@@ -0,0 +1,221 @@
+package statemachine
+
+import (
+    "context"
+    "errors"
+    "sync"
+    "testing"
+    "time"
+)
+
+// BlockingState implements State with an entry hook that waits for release
+type BlockingState struct {
+    MockState
+    entered chan struct{}
+    release chan struct{}
+}
+
+func (s *BlockingState) StateIn() error {
+    s.entered <- struct{}{}
+    <-s.release
+    return nil
+}
+
+// RecordingState implements TransitionState and records received payloads
+type RecordingState struct {
+    MockState
+    mu       sync.Mutex
+    payloads []interface{}
+}
+
+func (s *RecordingState) StateInWithTransition(t Transition) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.payloads = append(s.payloads, t.Payload)
+    return nil
+}
+
+func (s *RecordingState) Payloads() []interface{} {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return append([]interface{}(nil), s.payloads...)
+}
+
+func newStartedMachine(t *testing.T, states ...State) *StateMachine {
+    stateMap := StateMap{States: map[string]State{}}
+    for _, s := range states {
+        stateMap.States[s.GetName()] = s
+    }
+    sm := NewStateMachine(&MockStateMachine{allowChange: true}, stateMap)
+    if err := sm.Start(states[0].GetName()); err != nil {
+        t.Fatalf("Start failed: %v", err)
+    }
+    return sm
+}
+
+func TestAsyncStateMachine_OrderAndShutdown(t *testing.T) {
+    a1 := &RecordingState{MockState: MockState{name: "a"}}
+    b1 := &RecordingState{MockState: MockState{name: "b"}}
+    am := NewAsyncStateMachine(newStartedMachine(t, a1, b1), AsyncOptions{})
+
+    for i := 0; i < 10; i++ {
+        target := "b"
+        if i%2 == 1 {
+            target = "a"
+        }
+        if err := am.Send(Event{Target: target, Payload: i}); err != nil {
+            t.Fatalf("Send failed: %v", err)
+        }
+    }
+
+    // Shutdown drains the queue before returning
+    if err := am.Shutdown(context.Background()); err != nil {
+        t.Fatalf("Shutdown failed: %v", err)
+    }
+
+    got := append(b1.Payloads(), a1.Payloads()...)
+    if len(got) != 11 {
+        t.Fatalf("Expected 11 entries (start + 10 events), got %d", len(got))
+    }
+    for i, p := range b1.Payloads() {
+        if p != i*2 {
+            t.Errorf("Expected payload %d at position %d, got %v", i*2, i, p)
+        }
+    }
+
+    if err := am.Send(Event{Target: "a"}); !errors.Is(err, ErrShutdown) {
+        t.Errorf("Expected ErrShutdown after shutdown, got %v", err)
+    }
+}
+
+func TestAsyncStateMachine_SendAndWait(t *testing.T) {
+    am := NewAsyncStateMachine(newStartedMachine(t, &MockState{name: "a"}, &MockState{name: "b"}), AsyncOptions{})
+    defer am.Shutdown(context.Background())
+
+    if err := am.SendAndWait(context.Background(), Event{Target: "b"}); err != nil {
+        t.Errorf("Expected transition to succeed, got %v", err)
+    }
+    if state := am.GetCurrentState(); state.GetName() != "b" || !am.IsRunning() {
+        t.Errorf("Expected running in state b, got %s", state.GetName())
+    }
+    // Transitions must go through the mailbox
+    if _, ok := interface{}(am).(interface{ ChangeState(string) error }); ok {
+        t.Error("Expected AsyncStateMachine not to expose ChangeState")
+    }
+    if err := am.SendAndWait(context.Background(), Event{Target: "missing"}); err == nil {
+        t.Error("Expected transition to unknown state to fail")
+    }
+    for _, ev := range []Event{{}, {Fire: "b", Target: "b"}} {
+        if err := am.SendAndWait(context.Background(), ev); !errors.Is(err, ErrInvalidEvent) {
+            t.Errorf("Expected ErrInvalidEvent for %+v, got %v", ev, err)
+        }
+        if err := am.Send(ev); !errors.Is(err, ErrInvalidEvent) {
+            t.Errorf("Expected ErrInvalidEvent from Send for %+v, got %v", ev, err)
+        }
+    }
+}
+
+func TestAsyncStateMachine_Backpressure(t *testing.T) {
+    slow := &BlockingState{
+        MockState: MockState{name: "slow"},
+        entered:   make(chan struct{}, 1),
+        release:   make(chan struct{}),
+    }
+
+    tests := []struct {
+        name         string
+        backpressure Backpressure
+        expectErr    error
+        expectDrop   uint64
+    }{
+        {name: "Error", backpressure: BackpressureError, expectErr: ErrMailboxFull},
+        {name: "Drop", backpressure: BackpressureDrop, expectDrop: 1},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            am := NewAsyncStateMachine(newStartedMachine(t, &MockState{name: "a"}, slow),
+                AsyncOptions{MailboxSize: 1, Backpressure: tt.backpressure})
+
+            // First event occupies the loop, second fills the mailbox
+            if err := am.Send(Event{Target: "slow"}); err != nil {
+                t.Fatalf("Send failed: %v", err)
+            }
+            <-slow.entered
+            if err := am.Send(Event{Target: "a"}); err != nil {
+                t.Fatalf("Send failed: %v", err)
+            }
+
+            // Readers are not blocked by the slow hook
+            done := make(chan struct{})
+            go func() {
+                am.GetCurrentState()
+                close(done)
+            }()
+            select {
+            case <-done:
+            case <-time.After(time.Second):
+                t.Fatal("GetCurrentState blocked by a running hook")
+            }
+
+            if err := am.Send(Event{Target: "a"}); err != tt.expectErr {
+                t.Errorf("Expected %v, got %v", tt.expectErr, err)
+            }
+            if am.Dropped() != tt.expectDrop {
+                t.Errorf("Expected %d dropped events, got %d", tt.expectDrop, am.Dropped())
+            }
+
+            slow.release <- struct{}{}
+            if err := am.Shutdown(context.Background()); err != nil {
+                t.Fatalf("Shutdown failed: %v", err)
+            }
+        })
+    }
+}
+
+func TestAsyncStateMachine_ShutdownReleasesBlockedSend(t *testing.T) {
+    slow := &BlockingState{
+        MockState: MockState{name: "slow"},
+        entered:   make(chan struct{}, 1),
+        release:   make(chan struct{}),
+    }
+    am := NewAsyncStateMachine(newStartedMachine(t, &MockState{name: "a"}, slow),
+        AsyncOptions{MailboxSize: 1, Backpressure: BackpressureBlock})
+
+    // The loop is held by the slow hook and the mailbox is full
+    if err := am.Send(Event{Target: "slow"}); err != nil {
+        t.Fatalf("Send failed: %v", err)
+    }
+    <-slow.entered
+    if err := am.Send(Event{Target: "a"}); err != nil {
+        t.Fatalf("Send failed: %v", err)
+    }
+    blocked := make(chan error, 1)
+    go func() {
+        blocked <- am.Send(Event{Target: "a"})
+    }()
+    time.Sleep(10 * time.Millisecond) // let the sender block on the mailbox
+
+    ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+    defer cancel()
+    shutdown := make(chan error, 1)
+    go func() {
+        shutdown <- am.Shutdown(ctx)
+    }()
+    select {
+    case err := <-shutdown:
+        if !errors.Is(err, context.DeadlineExceeded) {
+            t.Errorf("Expected the deadline of the shutdown, got %v", err)
+        }
+    case <-time.After(time.Second):
+        t.Fatal("Shutdown did not honour its context")
+    }
+    if err := <-blocked; !errors.Is(err, ErrShutdown) {
+        t.Errorf("Expected the blocked Send to fail with ErrShutdown, got %v", err)
+    }
+
+    close(slow.release)
+    if err := am.Shutdown(context.Background()); err != nil {
+        t.Errorf("Shutdown failed: %v", err)
+    }
+}
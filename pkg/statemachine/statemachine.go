@@ -18,12 +18,36 @@ type StateMachineInterface interface {
     CheckStateChange(stateNow, newState State) (bool, error)
 }
 
+// Transition describes a state change together with the data it carries
+type Transition struct {
+    Event   string // empty for direct ChangeState calls
+    From    State
+    To      State
+    Payload interface{}
+}
+
+// TransitionState is an optional extension of State for states that want
+// the transition that entered them, including its payload
+type TransitionState interface {
+    State
+    StateInWithTransition(t Transition) error
+}
+
+// TransitionChecker is an optional extension of StateMachineInterface that
+// guards transitions with full transition metadata instead of CheckStateChange
+type TransitionChecker interface {
+    CheckTransition(t Transition) (bool, error)
+}
+
 // StateMap holds all available states
 type StateMap struct {
     States map[string]State
 }
 
 // StateMachine implements a thread-safe state machine
+//
+// Transitions are serialized by opMu while mu only guards the fields, so
+// slow hooks never block readers such as GetCurrentState
 type StateMachine struct {
     smi       StateMachineInterface
     stateMap  StateMap
@@ -31,62 +55,90 @@ type StateMachine struct {
     running   bool
     stateNow  State
     stateLast State
+    rules     transitionTable
+    listeners []func(Transition)
+    onError   []func(error)
+    busy      bool         // an operation holds opMu
+    deferred  []Event      // events raised while busy
+    completed []Transition // transitions not yet seen by listeners
+    opMu      sync.Mutex   // serializes Start and transitions
     mu        sync.RWMutex // RWMutex for concurrent access
 }
 
 // NewStateMachine creates a new instance of StateMachine
 func NewStateMachine(smi StateMachineInterface, stateMap StateMap) *StateMachine {
-    return &StateMachine{
+    sm := &StateMachine{
         smi:      smi,
         stateMap: stateMap,
         initing:  false,
         running:  false,
     }
+
+    // Let composite states such as Submachine know their parent
+    for _, state := range stateMap.States {
+        if a, ok := state.(interface{ attach(*StateMachine) }); ok {
+            a.attach(sm)
+        }
+    }
+    return sm
 }
 
 // Start initializes the state machine with the first state
 func (sm *StateMachine) Start(firstState string) error {
-    sm.mu.Lock()
-    defer sm.mu.Unlock()
+    sm.lockOp()
+    defer sm.unlockOp()
 
+    sm.mu.Lock()
     // Check if already running
     if sm.initing || sm.running {
+        sm.mu.Unlock()
         return fmt.Errorf("state machine already started")
     }
 
     // Validate first state
     state, exists := sm.stateMap.States[firstState]
     if !exists {
+        sm.mu.Unlock()
         return fmt.Errorf("state not found: %s", firstState)
     }
+    sm.initing = true
+    sm.mu.Unlock()
 
     // Initialize state machine
-    sm.initing = true
     if err := sm.smi.InitData(); err != nil {
-        sm.initing = false
+        sm.setIniting(false)
         return fmt.Errorf("init data failed: %w", err)
     }
 
     // Enter first state
-    sm.stateNow = state
-    if err := sm.stateNow.StateIn(); err != nil {
-        sm.initing = false
+    sm.setStates(state, nil)
+    if err := enterState(Transition{To: state}); err != nil {
+        sm.setIniting(false)
         return fmt.Errorf("state in failed: %w", err)
     }
 
     // Mark as running
+    sm.mu.Lock()
     sm.running = true
     sm.initing = false
+    sm.completed = append(sm.completed, Transition{To: state})
+    sm.mu.Unlock()
     return nil
 }
 
 // ChangeState triggers a state transition
 func (sm *StateMachine) ChangeState(stateName string) error {
-    sm.mu.Lock()
-    defer sm.mu.Unlock()
+    return sm.ChangeStateWithPayload(stateName, nil)
+}
+
+// ChangeStateWithPayload triggers a state transition that carries payload to
+// the guard and to the entering state
+func (sm *StateMachine) ChangeStateWithPayload(stateName string, payload interface{}) error {
+    sm.lockOp()
+    defer sm.unlockOp()
 
     // Validate current state
-    if !sm.running {
+    if !sm.IsRunning() {
         return fmt.Errorf("state machine not running")
     }
 
@@ -96,12 +148,13 @@ func (sm *StateMachine) ChangeState(stateName string) error {
         return fmt.Errorf("state not found: %s", stateName)
     }
 
-    return sm.doChangeState(stateName)
+    return sm.doChangeState("", stateName, payload)
 }
 
 // doChangeState performs the actual state transition
-// Note: This method assumes the caller holds the lock
-func (sm *StateMachine) doChangeState(stateName string) error {
+// Note: This method assumes the caller holds opMu, which makes it the only
+// writer of the state fields
+func (sm *StateMachine) doChangeState(event, stateName string, payload interface{}) error {
     // Validate current state
     if sm.stateNow == nil {
         return fmt.Errorf("current state is undefined")
@@ -109,7 +162,8 @@ func (sm *StateMachine) doChangeState(stateName string) error {
 
     // Get new state and check if transition is allowed
     newState := sm.stateMap.States[stateName]
-    canChange, err := sm.smi.CheckStateChange(sm.stateNow, newState)
+    t := Transition{Event: event, From: sm.stateNow, To: newState, Payload: payload}
+    canChange, err := sm.checkTransition(t)
     if err != nil {
         return fmt.Errorf("check state change failed: %w", err)
     }
@@ -125,18 +179,168 @@ func (sm *StateMachine) doChangeState(stateName string) error {
     }
 
     // Update states
-    sm.stateLast = sm.stateNow
-    sm.stateNow = newState
+    sm.setStates(newState, t.From)
 
     // Enter new state
-    if err := sm.stateNow.StateIn(); err != nil {
+    if err := enterState(t); err != nil {
         return fmt.Errorf("state in failed: %w", err)
     }
 
+    sm.mu.Lock()
     sm.stateLast = nil
+    sm.completed = append(sm.completed, t)
+    sm.mu.Unlock()
     return nil
 }
 
+// Stop exits the current state and stops the state machine
+func (sm *StateMachine) Stop() error {
+    sm.lockOp()
+    defer sm.unlockOp()
+
+    if !sm.IsRunning() {
+        return fmt.Errorf("state machine not running")
+    }
+
+    sm.mu.Lock()
+    sm.running = false
+    sm.mu.Unlock()
+
+    if err := sm.stateNow.StateOut(); err != nil {
+        return fmt.Errorf("state out failed: %w", err)
+    }
+    return nil
+}
+
+// OnTransition registers fn to be called after every successful transition,
+// including the one made by Start. Listeners run once the machine is
+// unlocked, so they may call back into it
+func (sm *StateMachine) OnTransition(fn func(Transition)) {
+    sm.mu.Lock()
+    defer sm.mu.Unlock()
+    sm.listeners = append(sm.listeners, fn)
+}
+
+// OnError registers fn to be called with the errors of events raised while
+// the machine was busy, such as the completion events of a Submachine, which
+// have no caller to return them to. Like listeners, fn runs once the machine
+// is unlocked
+func (sm *StateMachine) OnError(fn func(error)) {
+    sm.mu.Lock()
+    defer sm.mu.Unlock()
+    sm.onError = append(sm.onError, fn)
+}
+
+// lockOp acquires opMu and marks the machine busy
+func (sm *StateMachine) lockOp() {
+    sm.opMu.Lock()
+    sm.mu.Lock()
+    sm.busy = true
+    sm.mu.Unlock()
+}
+
+// unlockOp fires the events raised while busy, releases opMu and then
+// notifies listeners of the completed transitions and the failed events
+func (sm *StateMachine) unlockOp() {
+    var errs []error
+    for {
+        sm.mu.Lock()
+        if len(sm.deferred) == 0 {
+            sm.busy = false
+            sm.mu.Unlock()
+            break
+        }
+        ev := sm.deferred[0]
+        sm.deferred = sm.deferred[1:]
+        sm.mu.Unlock()
+
+        if err := sm.fire(ev.Fire, ev.Payload); err != nil {
+            errs = append(errs, raisedError(ev.Fire, err))
+        }
+    }
+
+    sm.mu.Lock()
+    completed := sm.completed
+    sm.completed = nil
+    listeners := sm.listeners
+    sm.mu.Unlock()
+    sm.opMu.Unlock()
+
+    for _, t := range completed {
+        for _, fn := range listeners {
+            fn(t)
+        }
+    }
+    for _, err := range errs {
+        sm.reportError(err)
+    }
+}
+
+// raise fires event now, or right after the running operation when the
+// machine is busy, which lets hooks trigger follow-up events safely. Errors
+// are reported to the OnError handlers
+func (sm *StateMachine) raise(event string, payload interface{}) {
+    sm.mu.Lock()
+    if sm.busy {
+        sm.deferred = append(sm.deferred, Event{Fire: event, Payload: payload})
+        sm.mu.Unlock()
+        return
+    }
+    sm.mu.Unlock()
+    if err := sm.Fire(event, payload); err != nil {
+        sm.reportError(raisedError(event, err))
+    }
+}
+
+// raisedError wraps the error of a raised event with its name
+func raisedError(event string, err error) error {
+    return fmt.Errorf("raised event %s: %w", event, err)
+}
+
+// reportError passes err to the OnError handlers
+// Note: This method must be called without opMu held
+func (sm *StateMachine) reportError(err error) {
+    sm.mu.RLock()
+    handlers := sm.onError
+    sm.mu.RUnlock()
+    for _, fn := range handlers {
+        fn(err)
+    }
+}
+
+// setStates updates the current and last state under the field lock
+func (sm *StateMachine) setStates(now, last State) {
+    sm.mu.Lock()
+    defer sm.mu.Unlock()
+    sm.stateNow = now
+    sm.stateLast = last
+}
+
+// setIniting updates the initing flag under the field lock
+func (sm *StateMachine) setIniting(initing bool) {
+    sm.mu.Lock()
+    defer sm.mu.Unlock()
+    sm.initing = initing
+}
+
+// checkTransition asks the guard whether t is allowed, preferring
+// TransitionChecker when the interface implements it
+func (sm *StateMachine) checkTransition(t Transition) (bool, error) {
+    if tc, ok := sm.smi.(TransitionChecker); ok {
+        return tc.CheckTransition(t)
+    }
+    return sm.smi.CheckStateChange(t.From, t.To)
+}
+
+// enterState calls the entry hook of t.To, handing over the transition when
+// the state implements TransitionState
+func enterState(t Transition) error {
+    if ts, ok := t.To.(TransitionState); ok {
+        return ts.StateInWithTransition(t)
+    }
+    return t.To.StateIn()
+}
+
 // GetCurrentState returns the current state
 func (sm *StateMachine) GetCurrentState() State {
     sm.mu.RLock()
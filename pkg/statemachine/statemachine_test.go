@@ -356,4 +356,69 @@ func TestStateMachine_CompleteWorkflow(t *testing.T) {
     if !sm.IsRunning() {
         t.Error("State machine should be running")
     }
-} 
\ No newline at end of file
+}
+
+// PayloadState implements TransitionState for testing
+type PayloadState struct {
+    MockState
+    entered []Transition
+}
+
+func (s *PayloadState) StateInWithTransition(t Transition) error {
+    s.entered = append(s.entered, t)
+    return s.stateInError
+}
+
+// GuardStateMachine implements TransitionChecker for testing
+type GuardStateMachine struct {
+    MockStateMachine
+    checked []Transition
+}
+
+func (g *GuardStateMachine) CheckTransition(t Transition) (bool, error) {
+    g.checked = append(g.checked, t)
+    return t.Payload != nil, nil
+}
+
+func TestStateMachine_ChangeStateWithPayload(t *testing.T) {
+    idle := &PayloadState{MockState: MockState{name: "idle"}}
+    errState := &PayloadState{MockState: MockState{name: "error"}}
+    stateMap := StateMap{
+        States: map[string]State{
+            "idle":  idle,
+            "error": errState,
+        },
+    }
+
+    guard := &GuardStateMachine{}
+    sm := NewStateMachine(guard, stateMap)
+    if err := sm.Start("idle"); err != nil {
+        t.Fatalf("Start failed: %v", err)
+    }
+
+    // Start delivers a transition without origin
+    if len(idle.entered) != 1 || idle.entered[0].From != nil || idle.entered[0].To != idle {
+        t.Fatalf("Expected start transition into idle, got %+v", idle.entered)
+    }
+
+    // Guard rejects transitions without payload
+    if err := sm.ChangeState("error"); err == nil {
+        t.Error("Expected guard to reject transition without payload")
+    }
+
+    cause := errors.New("disk full")
+    if err := sm.ChangeStateWithPayload("error", cause); err != nil {
+        t.Fatalf("ChangeStateWithPayload failed: %v", err)
+    }
+
+    if len(guard.checked) != 2 || guard.checked[1].Payload != cause {
+        t.Errorf("Expected guard to see payload, got %+v", guard.checked)
+    }
+    if len(errState.entered) != 1 {
+        t.Fatalf("Expected error state to be entered once, got %d", len(errState.entered))
+    }
+    got := errState.entered[0]
+    if got.From != idle || got.To != errState || got.Payload != cause {
+        t.Errorf("Unexpected transition delivered to state: %+v", got)
+    }
+}
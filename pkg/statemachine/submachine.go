@@ -0,0 +1,124 @@
+package statemachine
+
+import (
+    "fmt"
+    "sync"
+)
+
+// Submachine is a State that runs a child state machine while its parent is
+// in it. The child is built from a reusable definition every time the state
+// is entered and stopped when the parent leaves
+type Submachine struct {
+    name    string
+    build   func() *StateMachine
+    initial string
+    entries map[string]string // parent event -> child entry state
+    exits   map[string]string // child final state -> parent event
+    parent  *StateMachine
+    child   *StateMachine
+    mu      sync.Mutex
+}
+
+// NewSubmachine creates a state called name whose child machines come from
+// build and start in initial unless an entry point says otherwise
+func NewSubmachine(name string, build func() *StateMachine, initial string) *Submachine {
+    return &Submachine{
+        name:    name,
+        build:   build,
+        initial: initial,
+        entries: make(map[string]string),
+        exits:   make(map[string]string),
+    }
+}
+
+// Entry starts the child in childState when the parent enters this state
+// through parentEvent
+func (s *Submachine) Entry(parentEvent, childState string) *Submachine {
+    s.entries[parentEvent] = childState
+    return s
+}
+
+// Exit marks childState as final: reaching it fires parentEvent on the parent
+// with the payload of the child's transition
+func (s *Submachine) Exit(childState, parentEvent string) *Submachine {
+    s.exits[childState] = parentEvent
+    return s
+}
+
+// Child returns the running child machine, or nil outside of this state
+func (s *Submachine) Child() *StateMachine {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return s.child
+}
+
+// GetName returns the name of the state in the parent machine
+func (s *Submachine) GetName() string {
+    return s.name
+}
+
+// StateIn starts the child from its initial state
+func (s *Submachine) StateIn() error {
+    return s.StateInWithTransition(Transition{})
+}
+
+// StateInWithTransition starts the child from the entry point mapped to the
+// parent event, falling back to the initial state
+func (s *Submachine) StateInWithTransition(t Transition) error {
+    entry, ok := s.entries[t.Event]
+    if !ok {
+        entry = s.initial
+    }
+
+    child := s.build()
+    if child == nil {
+        return fmt.Errorf("submachine %s: definition built no machine", s.name)
+    }
+    child.OnTransition(func(ct Transition) {
+        s.childTransition(child, ct)
+    })
+
+    s.mu.Lock()
+    s.child = child
+    s.mu.Unlock()
+
+    if err := child.Start(entry); err != nil {
+        s.mu.Lock()
+        s.child = nil
+        s.mu.Unlock()
+        return fmt.Errorf("submachine %s: %w", s.name, err)
+    }
+    return nil
+}
+
+// StateOut stops the child if it is still running
+func (s *Submachine) StateOut() error {
+    s.mu.Lock()
+    child := s.child
+    s.child = nil
+    s.mu.Unlock()
+
+    if child == nil || !child.IsRunning() {
+        return nil
+    }
+    if err := child.Stop(); err != nil {
+        return fmt.Errorf("submachine %s: %w", s.name, err)
+    }
+    return nil
+}
+
+// attach records the parent machine, called by NewStateMachine
+func (s *Submachine) attach(parent *StateMachine) {
+    s.parent = parent
+}
+
+// childTransition raises the completion event on the parent once the child
+// reaches one of its final states. Errors of the event are reported to the
+// OnError handlers of the parent
+func (s *Submachine) childTransition(child *StateMachine, t Transition) {
+    event, final := s.exits[t.To.GetName()]
+    if !final || s.parent == nil || s.Child() != child {
+        return
+    }
+    s.parent.raise(event, t.Payload)
+}
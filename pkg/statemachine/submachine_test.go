@@ -0,0 +1,194 @@
+package statemachine
+
+import (
+    "strings"
+    "testing"
+)
+
+// newLoginMachine builds the reusable child flow used by the submachine tests
+func newLoginMachine() *StateMachine {
+    stateMap := StateMap{States: map[string]State{}}
+    for _, name := range []string{"username", "password", "done", "failed"} {
+        stateMap.States[name] = &MockState{name: name}
+    }
+    return NewStateMachine(&MockStateMachine{allowChange: true}, stateMap)
+}
+
+func newParentMachine(t *testing.T) (*StateMachine, *Submachine, *RecordingState) {
+    login := NewSubmachine("login", newLoginMachine, "username").
+        Entry("relogin", "password").
+        Exit("done", "loggedIn").
+        Exit("failed", "loginFailed")
+    dashboard := &RecordingState{MockState: MockState{name: "dashboard"}}
+
+    sm := NewStateMachine(&MockStateMachine{allowChange: true}, StateMap{
+        States: map[string]State{
+            "home":      &MockState{name: "home"},
+            "login":     login,
+            "dashboard": dashboard,
+        },
+    })
+    for _, rule := range [][3]string{
+        {"signIn", "home", "login"},
+        {"relogin", "dashboard", "login"},
+        {"loggedIn", "login", "dashboard"},
+        {"loginFailed", "login", "home"},
+        {"cancel", "login", "home"},
+    } {
+        if err := sm.AddTransition(rule[0], rule[1], rule[2]); err != nil {
+            t.Fatalf("AddTransition failed: %v", err)
+        }
+    }
+    if err := sm.Start("home"); err != nil {
+        t.Fatalf("Start failed: %v", err)
+    }
+    return sm, login, dashboard
+}
+
+func TestSubmachine_CompletionFiresParentEvent(t *testing.T) {
+    sm, login, dashboard := newParentMachine(t)
+
+    if err := sm.Fire("signIn", nil); err != nil {
+        t.Fatalf("Fire signIn failed: %v", err)
+    }
+    child := login.Child()
+    if child == nil || child.GetCurrentState().GetName() != "username" {
+        t.Fatal("Expected child to start in its initial state")
+    }
+
+    if err := child.ChangeState("password"); err != nil {
+        t.Fatalf("Child ChangeState failed: %v", err)
+    }
+    if err := child.ChangeStateWithPayload("done", "user-42"); err != nil {
+        t.Fatalf("Child ChangeState failed: %v", err)
+    }
+
+    if state := sm.GetCurrentState(); state.GetName() != "dashboard" {
+        t.Fatalf("Expected parent to complete into dashboard, got %s", state.GetName())
+    }
+    if payloads := dashboard.Payloads(); len(payloads) != 1 || payloads[0] != "user-42" {
+        t.Errorf("Expected child payload to reach parent state, got %v", payloads)
+    }
+    if login.Child() != nil || child.IsRunning() {
+        t.Error("Expected child to be stopped after completion")
+    }
+}
+
+func TestSubmachine_EntryPointsAndExit(t *testing.T) {
+    sm, login, _ := newParentMachine(t)
+
+    // Leaving early stops the child without completion
+    if err := sm.Fire("signIn", nil); err != nil {
+        t.Fatalf("Fire signIn failed: %v", err)
+    }
+    child := login.Child()
+    if err := sm.Fire("cancel", nil); err != nil {
+        t.Fatalf("Fire cancel failed: %v", err)
+    }
+    if child.IsRunning() {
+        t.Error("Expected child to stop when parent leaves the state")
+    }
+
+    // A failing child maps to a different parent event
+    if err := sm.Fire("signIn", nil); err != nil {
+        t.Fatalf("Fire signIn failed: %v", err)
+    }
+    if err := login.Child().ChangeState("failed"); err != nil {
+        t.Fatalf("Child ChangeState failed: %v", err)
+    }
+    if state := sm.GetCurrentState(); state.GetName() != "home" {
+        t.Fatalf("Expected loginFailed to return home, got %s", state.GetName())
+    }
+
+    // Entering through relogin starts at the mapped entry point
+    if err := sm.Fire("signIn", nil); err != nil {
+        t.Fatalf("Fire signIn failed: %v", err)
+    }
+    if err := login.Child().ChangeState("done"); err != nil {
+        t.Fatalf("Child ChangeState failed: %v", err)
+    }
+    if err := sm.Fire("relogin", nil); err != nil {
+        t.Fatalf("Fire relogin failed: %v", err)
+    }
+    if state := login.Child().GetCurrentState(); state.GetName() != "password" {
+        t.Errorf("Expected relogin to enter at password, got %s", state.GetName())
+    }
+}
+
+func TestSubmachine_ImmediateCompletion(t *testing.T) {
+    login := NewSubmachine("login", newLoginMachine, "done").Exit("done", "loggedIn")
+    sm := NewStateMachine(&MockStateMachine{allowChange: true}, StateMap{
+        States: map[string]State{
+            "home":      &MockState{name: "home"},
+            "login":     login,
+            "dashboard": &MockState{name: "dashboard"},
+        },
+    })
+    if err := sm.AddTransition("loggedIn", "login", "dashboard"); err != nil {
+        t.Fatalf("AddTransition failed: %v", err)
+    }
+    if err := sm.Start("home"); err != nil {
+        t.Fatalf("Start failed: %v", err)
+    }
+
+    // The child completes while the parent is still entering login
+    if err := sm.ChangeState("login"); err != nil {
+        t.Fatalf("ChangeState failed: %v", err)
+    }
+    if state := sm.GetCurrentState(); state.GetName() != "dashboard" {
+        t.Errorf("Expected deferred completion to reach dashboard, got %s", state.GetName())
+    }
+}
+
+func TestSubmachine_CompletionError(t *testing.T) {
+    login := NewSubmachine("login", newLoginMachine, "username").Exit("done", "loggedIn")
+    sm := NewStateMachine(&MockStateMachine{allowChange: true}, StateMap{
+        States: map[string]State{
+            "home":  &MockState{name: "home"},
+            "login": login,
+        },
+    })
+    var errs []error
+    sm.OnError(func(err error) {
+        errs = append(errs, err)
+    })
+    if err := sm.Start("home"); err != nil {
+        t.Fatalf("Start failed: %v", err)
+    }
+    if err := sm.ChangeState("login"); err != nil {
+        t.Fatalf("ChangeState failed: %v", err)
+    }
+
+    // The parent has no rule for the completion event
+    if err := login.Child().ChangeState("done"); err != nil {
+        t.Fatalf("Child ChangeState failed: %v", err)
+    }
+    if len(errs) != 1 || !strings.Contains(errs[0].Error(), "raised event loggedIn") {
+        t.Errorf("Expected the completion error to be reported, got %v", errs)
+    }
+    if state := sm.GetCurrentState(); state.GetName() != "login" {
+        t.Errorf("Expected parent to stay in login, got %s", state.GetName())
+    }
+
+    // Completing while the parent is busy reports the deferred error
+    login = NewSubmachine("login", newLoginMachine, "done").Exit("done", "loggedIn")
+    sm = NewStateMachine(&MockStateMachine{allowChange: true}, StateMap{
+        States: map[string]State{
+            "home":  &MockState{name: "home"},
+            "login": login,
+        },
+    })
+    errs = nil
+    sm.OnError(func(err error) {
+        errs = append(errs, err)
+    })
+    if err := sm.Start("home"); err != nil {
+        t.Fatalf("Start failed: %v", err)
+    }
+    if err := sm.ChangeState("login"); err != nil {
+        t.Fatalf("ChangeState failed: %v", err)
+    }
+    if len(errs) != 1 {
+        t.Errorf("Expected the deferred completion error to be reported, got %v", errs)
+    }
+}
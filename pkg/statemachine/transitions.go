@@ -0,0 +1,162 @@
+package statemachine
+
+import (
+    "errors"
+    "fmt"
+    "path"
+)
+
+// ErrNoTransition is returned by Fire when no rule matches the event in the
+// current state
+var ErrNoTransition = errors.New("no transition for event")
+
+// patternRule moves states whose name matches pattern to the state to
+type patternRule struct {
+    pattern string
+    to      string
+}
+
+// anyRule moves every state except the excluded ones to the state to
+type anyRule struct {
+    to     string
+    except map[string]bool
+}
+
+// transitionTable holds the event rules of a state machine. Rules are
+// resolved in priority order: the exact source state first, then patterns in
+// registration order, then the any-state rule
+type transitionTable struct {
+    exact    map[string]map[string]string // event -> from -> to
+    patterns map[string][]patternRule
+    any      map[string]anyRule
+}
+
+// resolve returns the target state of event when leaving from
+func (tt *transitionTable) resolve(event, from string) (string, bool) {
+    if to, ok := tt.exact[event][from]; ok {
+        return to, true
+    }
+    for _, rule := range tt.patterns[event] {
+        if matched, _ := path.Match(rule.pattern, from); matched {
+            return rule.to, true
+        }
+    }
+    if rule, ok := tt.any[event]; ok && !rule.except[from] {
+        return rule.to, true
+    }
+    return "", false
+}
+
+// AddTransition registers event as moving from the state from to the state to.
+// State-specific rules take priority over pattern and any-state rules
+func (sm *StateMachine) AddTransition(event, from, to string) error {
+    sm.mu.Lock()
+    defer sm.mu.Unlock()
+
+    if err := sm.checkStates(from, to); err != nil {
+        return err
+    }
+
+    if sm.rules.exact == nil {
+        sm.rules.exact = make(map[string]map[string]string)
+    }
+    if sm.rules.exact[event] == nil {
+        sm.rules.exact[event] = make(map[string]string)
+    }
+    if _, exists := sm.rules.exact[event][from]; exists {
+        return fmt.Errorf("transition already defined: %s from %s", event, from)
+    }
+    sm.rules.exact[event][from] = to
+    return nil
+}
+
+// AddPatternTransition registers event for every state whose name matches
+// pattern (path.Match syntax). Patterns are tried in registration order after
+// state-specific rules and before the any-state rule
+func (sm *StateMachine) AddPatternTransition(event, pattern, to string) error {
+    sm.mu.Lock()
+    defer sm.mu.Unlock()
+
+    if _, err := path.Match(pattern, ""); err != nil {
+        return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+    }
+    if err := sm.checkStates(to); err != nil {
+        return err
+    }
+
+    if sm.rules.patterns == nil {
+        sm.rules.patterns = make(map[string][]patternRule)
+    }
+    sm.rules.patterns[event] = append(sm.rules.patterns[event], patternRule{pattern: pattern, to: to})
+    return nil
+}
+
+// AddAnyTransition registers event as valid from every state except the ones
+// listed in except. It has the lowest priority of all rules
+func (sm *StateMachine) AddAnyTransition(event, to string, except ...string) error {
+    sm.mu.Lock()
+    defer sm.mu.Unlock()
+
+    if err := sm.checkStates(append([]string{to}, except...)...); err != nil {
+        return err
+    }
+
+    if sm.rules.any == nil {
+        sm.rules.any = make(map[string]anyRule)
+    }
+    if _, exists := sm.rules.any[event]; exists {
+        return fmt.Errorf("any-state transition already defined: %s", event)
+    }
+    rule := anyRule{to: to, except: make(map[string]bool, len(except))}
+    for _, name := range except {
+        rule.except[name] = true
+    }
+    sm.rules.any[event] = rule
+    return nil
+}
+
+// Fire triggers the transition registered for event in the current state
+func (sm *StateMachine) Fire(event string, payload interface{}) error {
+    sm.lockOp()
+    defer sm.unlockOp()
+    return sm.fire(event, payload)
+}
+
+// fire resolves and performs the transition for event
+// Note: This method assumes the caller holds opMu
+func (sm *StateMachine) fire(event string, payload interface{}) error {
+    if !sm.IsRunning() {
+        return fmt.Errorf("state machine not running")
+    }
+
+    sm.mu.RLock()
+    to, ok := sm.rules.resolve(event, sm.stateNow.GetName())
+    sm.mu.RUnlock()
+    if !ok {
+        return fmt.Errorf("%w: %s in state %s", ErrNoTransition, event, sm.stateNow.GetName())
+    }
+
+    return sm.doChangeState(event, to, payload)
+}
+
+// dispatch fires the event or changes to the target state named by ev
+func (sm *StateMachine) dispatch(ev Event) error {
+    if err := ev.validate(); err != nil {
+        return err
+    }
+    if ev.Fire != "" {
+        return sm.Fire(ev.Fire, ev.Payload)
+    }
+    return sm.ChangeStateWithPayload(ev.Target, ev.Payload)
+}
+
+// checkStates verifies that every name refers to a known state
+// Note: This method assumes the caller holds mu
+func (sm *StateMachine) checkStates(names ...string) error {
+    for _, name := range names {
+        if _, exists := sm.stateMap.States[name]; !exists {
+            return fmt.Errorf("state not found: %s", name)
+        }
+    }
+    return nil
+}
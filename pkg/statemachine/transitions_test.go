@@ -0,0 +1,121 @@
+package statemachine
+
+import (
+    "context"
+    "errors"
+    "testing"
+)
+
+func newRuleMachine(t *testing.T) *StateMachine {
+    stateMap := StateMap{States: map[string]State{}}
+    for _, name := range []string{"idle", "loading", "loadingAssets", "playing", "error", "boot"} {
+        stateMap.States[name] = &MockState{name: name}
+    }
+    return NewStateMachine(&MockStateMachine{allowChange: true}, stateMap)
+}
+
+func TestStateMachine_Fire(t *testing.T) {
+    sm := newRuleMachine(t)
+
+    mustAdd := func(err error) {
+        t.Helper()
+        if err != nil {
+            t.Fatalf("Adding transition failed: %v", err)
+        }
+    }
+    mustAdd(sm.AddTransition("start", "idle", "loading"))
+    mustAdd(sm.AddTransition("done", "loading", "playing"))
+    mustAdd(sm.AddPatternTransition("done", "loading*", "idle"))
+    mustAdd(sm.AddAnyTransition("fatalError", "error", "boot"))
+    mustAdd(sm.AddAnyTransition("reset", "idle"))
+    mustAdd(sm.AddTransition("reset", "error", "boot"))
+
+    if err := sm.Start("idle"); err != nil {
+        t.Fatalf("Start failed: %v", err)
+    }
+
+    steps := []struct {
+        event   string
+        expect  string
+        wantErr bool
+    }{
+        {event: "start", expect: "loading"},
+        // State-specific rule wins over the matching pattern
+        {event: "done", expect: "playing"},
+        {event: "done", expect: "playing", wantErr: true},
+        {event: "fatalError", expect: "error"},
+        // State-specific rule wins over the any-state rule
+        {event: "reset", expect: "boot"},
+        // boot is excluded from fatalError
+        {event: "fatalError", expect: "boot", wantErr: true},
+        {event: "reset", expect: "idle"},
+    }
+
+    for _, step := range steps {
+        err := sm.Fire(step.event, nil)
+        if step.wantErr && !errors.Is(err, ErrNoTransition) {
+            t.Errorf("Expected ErrNoTransition for %s, got %v", step.event, err)
+        }
+        if !step.wantErr && err != nil {
+            t.Errorf("Fire %s failed: %v", step.event, err)
+        }
+        if state := sm.GetCurrentState(); state.GetName() != step.expect {
+            t.Errorf("After %s expected state %s, got %s", step.event, step.expect, state.GetName())
+        }
+    }
+
+    // Pattern applies to states without a specific rule
+    if err := sm.ChangeState("loadingAssets"); err != nil {
+        t.Fatalf("ChangeState failed: %v", err)
+    }
+    if err := sm.Fire("done", nil); err != nil {
+        t.Fatalf("Fire done failed: %v", err)
+    }
+    if state := sm.GetCurrentState(); state.GetName() != "idle" {
+        t.Errorf("Expected pattern transition to idle, got %s", state.GetName())
+    }
+}
+
+func TestStateMachine_AddTransitionValidation(t *testing.T) {
+    sm := newRuleMachine(t)
+
+    if err := sm.AddTransition("go", "idle", "nowhere"); err == nil {
+        t.Error("Expected unknown target state to be rejected")
+    }
+    if err := sm.AddTransition("go", "idle", "loading"); err != nil {
+        t.Fatalf("AddTransition failed: %v", err)
+    }
+    if err := sm.AddTransition("go", "idle", "playing"); err == nil {
+        t.Error("Expected duplicate transition to be rejected")
+    }
+    if err := sm.AddPatternTransition("go", "[", "idle"); err == nil {
+        t.Error("Expected malformed pattern to be rejected")
+    }
+    if err := sm.AddAnyTransition("reset", "idle", "nowhere"); err == nil {
+        t.Error("Expected unknown excluded state to be rejected")
+    }
+}
+
+func TestAsyncStateMachine_FiresEvents(t *testing.T) {
+    sm := newRuleMachine(t)
+    if err := sm.AddAnyTransition("fatalError", "error"); err != nil {
+        t.Fatalf("AddAnyTransition failed: %v", err)
+    }
+    if err := sm.Start("idle"); err != nil {
+        t.Fatalf("Start failed: %v", err)
+    }
+
+    am := NewAsyncStateMachine(sm, AsyncOptions{})
+    defer am.Shutdown(context.Background())
+
+    // An event name is not taken for a state
+    if err := am.SendAndWait(context.Background(), Event{Target: "fatalError"}); err == nil {
+        t.Error("Expected Target to name a state, not an event")
+    }
+    if err := am.SendAndWait(context.Background(), Event{Fire: "fatalError"}); err != nil {
+        t.Fatalf("SendAndWait failed: %v", err)
+    }
+    if state := am.GetCurrentState(); state.GetName() != "error" {
+        t.Errorf("Expected state error, got %s", state.GetName())
+    }
+}